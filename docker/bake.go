@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+const bakeDefaultTarget = "default"
+
+var ErrInvalidBake = errors.New("invalid bake")
+
+// Bake defines Docker bake parameters.
+type Bake struct {
+	Files        []string // Docker bake definition files
+	Targets      []string // Docker bake targets or groups
+	Set          []string // Docker bake target overrides
+	MetadataFile string   // Docker bake metadata file
+}
+
+// helper function to create the docker buildx bake command.
+func (k *Bake) Run(b *Build, env []string) *plugin_exec.Cmd {
+	args := []string{
+		"buildx",
+		"bake",
+	}
+
+	for _, file := range k.Files {
+		args = append(args, "-f", file)
+	}
+
+	if !b.Dryrun && b.Output == "" {
+		args = append(args, "--push")
+	}
+
+	if b.Pull {
+		args = append(args, "--pull")
+	}
+
+	if b.NoCache {
+		args = append(args, "--no-cache")
+	}
+
+	if b.Quiet {
+		args = append(args, "--progress", "quiet")
+	}
+
+	if k.MetadataFile != "" {
+		args = append(args, "--metadata-file", k.MetadataFile)
+	}
+
+	for _, target := range k.targets() {
+		for _, override := range k.overrides(b, target) {
+			args = append(args, "--set", override)
+		}
+	}
+
+	for _, override := range k.Set {
+		args = append(args, "--set", override)
+	}
+
+	args = append(args, k.Targets...)
+
+	cmd := plugin_exec.Command(dockerBin, args...)
+
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
+
+// Validate checks that tags are only set for a single target, as all selected targets would
+// otherwise push to the same image references.
+func (k *Bake) Validate(b *Build) error {
+	if len(b.Refs()) > 0 && len(k.targets()) > 1 {
+		return fmt.Errorf("%w: tags can only be set for a single target, define the tags of %s in the bake file",
+			ErrInvalidBake, strings.Join(k.targets(), ", "))
+	}
+
+	return nil
+}
+
+// helper function to get the selected targets or the bake default target.
+func (k *Bake) targets() []string {
+	if len(k.Targets) == 0 {
+		return []string{bakeDefaultTarget}
+	}
+
+	return k.Targets
+}
+
+// helper function to map the build settings to bake overrides for the given target.
+func (k *Bake) overrides(b *Build, target string) []string {
+	overrides := make([]string, 0)
+
+	for _, ref := range b.Refs() {
+		overrides = append(overrides, fmt.Sprintf("%s.tags=%s", target, ref))
+	}
+
+	for _, label := range b.Labels {
+		key, value, _ := strings.Cut(label, "=")
+		overrides = append(overrides, fmt.Sprintf("%s.labels.%s=%s", target, key, value))
+	}
+
+	for _, cache := range b.CacheFrom {
+		overrides = append(overrides, fmt.Sprintf("%s.cache-from=%s", target, cache))
+	}
+
+	for _, cache := range b.CacheTo {
+		overrides = append(overrides, fmt.Sprintf("%s.cache-to=%s", target, cache))
+	}
+
+	if b.Output != "" {
+		overrides = append(overrides, fmt.Sprintf("%s.output=%s", target, b.Output))
+	}
+
+	return overrides
+}
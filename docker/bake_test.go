@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBakeRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		bake  Bake
+		build Build
+		want  []string
+	}{
+		{
+			name: "default target",
+			bake: Bake{},
+			build: Build{
+				Repo: "example/repo",
+				Tags: []string{"latest"},
+			},
+			want: []string{
+				dockerBin, "buildx", "bake", "--push",
+				"--set", "default.tags=example/repo:latest",
+			},
+		},
+		{
+			name: "selected targets with overrides",
+			bake: Bake{
+				Files:   []string{"docker-bake.hcl"},
+				Targets: []string{"api", "web"},
+				Set:     []string{"*.platform=linux/amd64"},
+			},
+			build: Build{
+				Repo:      "example/repo",
+				Labels:    []string{"org.opencontainers.image.revision=abc123"},
+				CacheFrom: []string{"type=registry,ref=example/repo:cache"},
+				CacheTo:   []string{"type=inline", "type=local,dest=/cache"},
+				Dryrun:    true,
+			},
+			want: []string{
+				dockerBin, "buildx", "bake", "-f", "docker-bake.hcl",
+				"--set", "api.labels.org.opencontainers.image.revision=abc123",
+				"--set", "api.cache-from=type=registry,ref=example/repo:cache",
+				"--set", "api.cache-to=type=inline",
+				"--set", "api.cache-to=type=local,dest=/cache",
+				"--set", "web.labels.org.opencontainers.image.revision=abc123",
+				"--set", "web.cache-from=type=registry,ref=example/repo:cache",
+				"--set", "web.cache-to=type=inline",
+				"--set", "web.cache-to=type=local,dest=/cache",
+				"--set", "*.platform=linux/amd64",
+				"api", "web",
+			},
+		},
+		{
+			name: "no tags",
+			bake: Bake{},
+			build: Build{
+				Repo: "example/repo",
+			},
+			want: []string{
+				dockerBin, "buildx", "bake", "--push",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.bake.Run(&tt.build, nil)
+			assert.Equal(t, tt.want, cmd.Args)
+		})
+	}
+}
+
+func TestBakeValidate(t *testing.T) {
+	build := &Build{Repo: "example/repo", Tags: []string{"latest"}}
+
+	assert.NoError(t, (&Bake{}).Validate(build))
+	assert.NoError(t, (&Bake{Targets: []string{"api"}}).Validate(build))
+	assert.NoError(t, (&Bake{Targets: []string{"api", "web"}}).Validate(&Build{Repo: "example/repo"}))
+	assert.ErrorIs(t, (&Bake{Targets: []string{"api", "web"}}).Validate(build), ErrInvalidBake)
+}
@@ -0,0 +1,341 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	CacheTypeRegistry = "registry"
+	CacheTypeLocal    = "local"
+	CacheTypeInline   = "inline"
+	CacheTypeS3       = "s3"
+
+	cacheTagPrefix = "buildcache-"
+)
+
+var ErrInvalidCache = errors.New("invalid cache config")
+
+// Cache defines the structured build cache parameters.
+type Cache struct {
+	Type        string   // Cache backend type
+	Mode        string   // Cache export mode
+	Compression string   // Cache compression type
+	Ref         string   // Cache reference, image name or directory depending on the type
+	Options     []string // Cache backend options
+	Auto        bool     // Cache reference derived from the git branch
+	Rotate      bool     // Cache rotation for local caches
+	MaxSize     int      // Cache size cap of rotated local caches in MiB
+
+	exportDir string
+}
+
+// Enabled returns true if a structured cache is configured.
+func (c *Cache) Enabled() bool {
+	return c.Type != ""
+}
+
+// Validate checks the cache options for the configured cache type.
+func (c *Cache) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if !slices.Contains([]string{CacheTypeRegistry, CacheTypeLocal, CacheTypeInline, CacheTypeS3}, c.Type) {
+		return fmt.Errorf("%w: unsupported type %s", ErrInvalidCache, c.Type)
+	}
+
+	if !slices.Contains([]string{"", "min", "max"}, c.Mode) {
+		return fmt.Errorf("%w: unsupported mode %s", ErrInvalidCache, c.Mode)
+	}
+
+	if !slices.Contains([]string{"", "gzip", "estargz", "zstd", "uncompressed"}, c.Compression) {
+		return fmt.Errorf("%w: unsupported compression %s", ErrInvalidCache, c.Compression)
+	}
+
+	if c.Rotate && c.Type != CacheTypeLocal {
+		return fmt.Errorf("%w: rotation is only supported by local cache", ErrInvalidCache)
+	}
+
+	if c.MaxSize < 0 {
+		return fmt.Errorf("%w: max size must not be negative", ErrInvalidCache)
+	}
+
+	for _, option := range c.Options {
+		if key, _, ok := strings.Cut(option, "="); !ok || key == "" {
+			return fmt.Errorf("%w: option %s must be in the format key=value", ErrInvalidCache, option)
+		}
+	}
+
+	switch c.Type {
+	case CacheTypeInline:
+		if c.Mode == "max" || c.Compression != "" {
+			return fmt.Errorf("%w: mode and compression are not supported by inline cache", ErrInvalidCache)
+		}
+	case CacheTypeLocal:
+		if c.Ref == "" {
+			return fmt.Errorf("%w: local cache requires a directory as ref", ErrInvalidCache)
+		}
+	case CacheTypeS3:
+		if !c.hasOption("bucket") || !c.hasOption("region") {
+			return fmt.Errorf("%w: s3 cache requires the bucket and region options", ErrInvalidCache)
+		}
+	}
+
+	return nil
+}
+
+// From returns the cache import entries. If auto mode is enabled, the cache of the current
+// branch is used with a fallback to the cache of the default branch.
+func (c *Cache) From(repo, branch, defaultBranch string) []string {
+	from := make([]string, 0)
+
+	for _, ref := range c.refs(repo, branch, defaultBranch) {
+		if c.Rotate {
+			if ref = c.rotatedSrc(ref); ref == "" {
+				continue
+			}
+		}
+
+		from = append(from, c.entry(false, ref))
+	}
+
+	return from
+}
+
+// To returns the cache export entry for the current branch.
+func (c *Cache) To(repo, branch, defaultBranch string) string {
+	if c.Type == CacheTypeInline {
+		return c.entry(true, "")
+	}
+
+	refs := c.refs(repo, branch, defaultBranch)
+	if len(refs) == 0 {
+		return ""
+	}
+
+	if c.Rotate {
+		return c.entry(true, c.rotatedDest(refs[0]))
+	}
+
+	return c.entry(true, refs[0])
+}
+
+// JoinCacheEntries joins cache entry fragments that were split on unescaped commas. A new entry
+// starts with a fragment that defines a type if the current entry already has a type, so a single
+// unescaped entry like `type=registry,ref=example/repo:cache,mode=max` is kept as one entry.
+func JoinCacheEntries(fragments []string) []string {
+	entries := make([]string, 0, len(fragments))
+
+	for _, fragment := range fragments {
+		last := len(entries) - 1
+
+		switch {
+		case last < 0,
+			!strings.Contains(fragment, "="),
+			!strings.Contains(entries[last], "="),
+			strings.HasPrefix(fragment, "type=") && hasCacheType(entries[last]):
+			entries = append(entries, fragment)
+		default:
+			entries[last] += "," + fragment
+		}
+	}
+
+	return entries
+}
+
+// helper function to check whether a cache entry defines a type.
+func hasCacheType(entry string) bool {
+	for _, field := range strings.Split(entry, ",") {
+		if strings.HasPrefix(field, "type=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateCacheEntry checks a raw buildx cache export entry for a supported type
+// and the required backend options.
+func ValidateCacheEntry(entry string) error {
+	if entry == "" {
+		return fmt.Errorf("%w: empty cache entry", ErrInvalidCache)
+	}
+
+	// entries without attributes are a shorthand for a registry cache reference
+	if !strings.Contains(entry, "=") {
+		return nil
+	}
+
+	attrs := make(map[string]string)
+
+	for _, field := range strings.Split(entry, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("%w: attribute %s of cache entry %s must be in the format key=value", ErrInvalidCache, field, entry)
+		}
+
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	cacheType, ok := attrs["type"]
+	if !ok {
+		return fmt.Errorf("%w: cache entry %s requires a type", ErrInvalidCache, entry)
+	}
+
+	var required []string
+
+	switch cacheType {
+	case CacheTypeRegistry:
+		required = []string{"ref"}
+	case CacheTypeLocal:
+		required = []string{"dest"}
+	case CacheTypeS3:
+		required = []string{"bucket", "region"}
+	case CacheTypeInline, "gha", "azblob":
+	default:
+		return fmt.Errorf("%w: unsupported type %s in cache entry %s", ErrInvalidCache, cacheType, entry)
+	}
+
+	for _, key := range required {
+		if attrs[key] == "" {
+			return fmt.Errorf("%w: cache entry %s requires the %s attribute", ErrInvalidCache, entry, key)
+		}
+	}
+
+	if mode, ok := attrs["mode"]; ok && !slices.Contains([]string{"min", "max"}, mode) {
+		return fmt.Errorf("%w: unsupported mode %s in cache entry %s", ErrInvalidCache, mode, entry)
+	}
+
+	return nil
+}
+
+// ApplyCache adds the import and export entries of the structured cache config to the build.
+func (b *Build) ApplyCache(branch, defaultBranch string) error {
+	if !b.Cache.Enabled() {
+		return nil
+	}
+
+	if err := b.Cache.Validate(); err != nil {
+		return err
+	}
+
+	if b.Cache.Type == CacheTypeRegistry && b.Cache.Ref == "" && (!b.Cache.Auto || b.Repo == "") {
+		return fmt.Errorf("%w: registry cache requires a ref or auto mode with a repo", ErrInvalidCache)
+	}
+
+	// matrix builds share the cache config, each build gets its own rotated cache root to
+	// not remove the cache exports of other builds
+	if b.Cache.Rotate && b.Name != "" {
+		b.Cache.Ref = strings.TrimRight(b.Cache.Ref, "/") + "/" + SanitizeTag(b.Name)
+	}
+
+	b.CacheFrom = append(b.CacheFrom, b.Cache.From(b.Repo, branch, defaultBranch)...)
+
+	if to := b.Cache.To(b.Repo, branch, defaultBranch); to != "" {
+		b.CacheTo = append(b.CacheTo, to)
+	}
+
+	return nil
+}
+
+// helper function to resolve the cache references, the current branch reference first.
+func (c *Cache) refs(repo, branch, defaultBranch string) []string {
+	if !c.Auto || c.Type == CacheTypeInline {
+		if c.Ref == "" && c.Type != CacheTypeS3 {
+			return nil
+		}
+
+		return []string{c.Ref}
+	}
+
+	base := c.Ref
+	if base == "" && c.Type != CacheTypeS3 {
+		base = repo
+	}
+
+	refs := make([]string, 0)
+
+	for _, b := range []string{branch, defaultBranch} {
+		if b == "" {
+			continue
+		}
+
+		ref := c.branchRef(base, b)
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// helper function to derive the branch specific cache reference from the base reference.
+func (c *Cache) branchRef(base, branch string) string {
+	tag := cacheTagPrefix + SanitizeTag(branch)
+
+	switch c.Type {
+	case CacheTypeLocal:
+		return strings.TrimRight(base, "/") + "/" + tag
+	case CacheTypeS3:
+		if base == "" {
+			return tag
+		}
+
+		return base + "-" + tag
+	default:
+		return base + ":" + tag
+	}
+}
+
+// helper function to create a buildx cache entry.
+func (c *Cache) entry(export bool, ref string) string {
+	attrs := []string{"type=" + c.Type}
+
+	// inline cache is imported from the image in the registry
+	if c.Type == CacheTypeInline && !export {
+		attrs = []string{"type=" + CacheTypeRegistry}
+	}
+
+	switch c.Type {
+	case CacheTypeRegistry, CacheTypeInline:
+		if ref != "" {
+			attrs = append(attrs, "ref="+ref)
+		}
+	case CacheTypeLocal:
+		if export {
+			attrs = append(attrs, "dest="+ref)
+		} else {
+			attrs = append(attrs, "src="+ref)
+		}
+	case CacheTypeS3:
+		if ref != "" {
+			attrs = append(attrs, "name="+ref)
+		}
+	}
+
+	if export && c.Mode != "" {
+		attrs = append(attrs, "mode="+c.Mode)
+	}
+
+	if export && c.Compression != "" {
+		attrs = append(attrs, "compression="+c.Compression)
+	}
+
+	attrs = append(attrs, c.Options...)
+
+	return strings.Join(attrs, ",")
+}
+
+// helper function to check if a backend option is set.
+func (c *Cache) hasOption(key string) bool {
+	for _, option := range c.Options {
+		if k, _, _ := strings.Cut(option, "="); k == key {
+			return true
+		}
+	}
+
+	return false
+}
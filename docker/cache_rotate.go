@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	localCacheCurrent = "current"
+	localCachePrefix  = "cache-"
+	bytesPerMiB       = 1024 * 1024
+)
+
+var ErrCacheSizeExceeded = errors.New("cache size limit exceeded")
+
+// helper function to get the import directory of a rotated local cache. If the cache
+// does not exist yet, an empty string is returned.
+func (c *Cache) rotatedSrc(dir string) string {
+	current := filepath.Join(dir, localCacheCurrent)
+
+	if _, err := os.Stat(current); err != nil {
+		return ""
+	}
+
+	return current
+}
+
+// helper function to create a fresh export directory name for a rotated local cache.
+func (c *Cache) rotatedDest(dir string) string {
+	c.exportDir = filepath.Join(dir, localCachePrefix+strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	return c.exportDir
+}
+
+// Commit swaps the current rotated local cache with the fresh cache export of a successful
+// build and removes all previous cache exports. If the fresh export exceeds the size cap, it
+// is discarded and the previous cache is kept.
+func (c *Cache) Commit() error {
+	if c.exportDir == "" {
+		return nil
+	}
+
+	if c.MaxSize > 0 {
+		size, err := dirSize(c.exportDir)
+		if err != nil {
+			return fmt.Errorf("failed to get cache size: %w", err)
+		}
+
+		if size > int64(c.MaxSize)*bytesPerMiB {
+			if err := c.Discard(); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("%w: %d MiB > %d MiB", ErrCacheSizeExceeded, size/bytesPerMiB, c.MaxSize)
+		}
+	}
+
+	root := filepath.Dir(c.exportDir)
+	current := filepath.Join(root, localCacheCurrent)
+	tmp := current + ".tmp"
+
+	// replace the symlink by an atomic rename to never expose a partial cache
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Symlink(filepath.Base(c.exportDir), tmp); err != nil {
+		return fmt.Errorf("failed to link cache: %w", err)
+	}
+
+	if err := os.Rename(tmp, current); err != nil {
+		return fmt.Errorf("failed to swap cache: %w", err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, localCachePrefix) || name == filepath.Base(c.exportDir) {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(root, name)); err != nil {
+			return fmt.Errorf("failed to remove previous cache: %w", err)
+		}
+	}
+
+	c.exportDir = ""
+
+	return nil
+}
+
+// Discard removes the fresh cache export, e.g. after a failed build.
+func (c *Cache) Discard() error {
+	if c.exportDir == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(c.exportDir); err != nil {
+		return fmt.Errorf("failed to remove cache: %w", err)
+	}
+
+	c.exportDir = ""
+
+	return nil
+}
+
+// helper function to calculate the total size of all files in a directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheRotate(t *testing.T) {
+	dir := t.TempDir()
+	cache := Cache{Type: CacheTypeLocal, Ref: dir, Rotate: true}
+
+	assert.Empty(t, cache.From("", "", ""))
+
+	first := cache.To("", "", "")
+	assert.Contains(t, first, "type=local,dest="+filepath.Join(dir, localCachePrefix))
+	assert.NoError(t, os.MkdirAll(cache.exportDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(cache.exportDir, "index.json"), []byte("{}"), 0o600))
+
+	firstDir := cache.exportDir
+
+	assert.NoError(t, cache.Commit())
+	assert.Equal(t, []string{"type=local,src=" + filepath.Join(dir, localCacheCurrent)}, cache.From("", "", ""))
+
+	_ = cache.To("", "", "")
+	assert.NoError(t, os.MkdirAll(cache.exportDir, 0o755))
+
+	secondDir := cache.exportDir
+
+	assert.NoError(t, cache.Commit())
+	assert.NoDirExists(t, firstDir)
+	assert.DirExists(t, secondDir)
+
+	target, err := os.Readlink(filepath.Join(dir, localCacheCurrent))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(secondDir), target)
+}
+
+func TestCacheRotateMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	cache := Cache{Type: CacheTypeLocal, Ref: dir, Rotate: true, MaxSize: 1}
+
+	_ = cache.To("", "", "")
+	assert.NoError(t, os.MkdirAll(cache.exportDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(cache.exportDir, "blob"), make([]byte, 2*bytesPerMiB), 0o600))
+
+	exportDir := cache.exportDir
+
+	assert.ErrorIs(t, cache.Commit(), ErrCacheSizeExceeded)
+	assert.NoDirExists(t, exportDir)
+	assert.NoFileExists(t, filepath.Join(dir, localCacheCurrent))
+}
+
+func TestCacheDiscard(t *testing.T) {
+	dir := t.TempDir()
+	cache := Cache{Type: CacheTypeLocal, Ref: dir, Rotate: true}
+
+	_ = cache.To("", "", "")
+	assert.NoError(t, os.MkdirAll(cache.exportDir, 0o755))
+
+	exportDir := cache.exportDir
+
+	assert.NoError(t, cache.Discard())
+	assert.NoDirExists(t, exportDir)
+	assert.NoError(t, cache.Commit())
+}
+
+func TestCacheRotateMatrix(t *testing.T) {
+	dir := t.TempDir()
+	builds := []*Build{
+		{Name: "api", Cache: Cache{Type: CacheTypeLocal, Ref: dir, Rotate: true}},
+		{Name: "web", Cache: Cache{Type: CacheTypeLocal, Ref: dir, Rotate: true}},
+	}
+
+	for _, build := range builds {
+		assert.NoError(t, build.ApplyCache("", ""))
+		assert.NoError(t, os.MkdirAll(build.Cache.exportDir, 0o755))
+	}
+
+	for _, build := range builds {
+		assert.NoError(t, build.Cache.Commit())
+	}
+
+	for _, name := range []string{"api", "web"} {
+		target, err := os.Readlink(filepath.Join(dir, name, localCacheCurrent))
+		assert.NoError(t, err)
+		assert.DirExists(t, filepath.Join(dir, name, target))
+	}
+}
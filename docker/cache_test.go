@@ -0,0 +1,296 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cache   Cache
+		wantErr bool
+	}{
+		{
+			name:  "disabled",
+			cache: Cache{},
+		},
+		{
+			name:  "registry cache",
+			cache: Cache{Type: CacheTypeRegistry, Mode: "max", Compression: "zstd"},
+		},
+		{
+			name:    "unsupported type",
+			cache:   Cache{Type: "gha2"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported mode",
+			cache:   Cache{Type: CacheTypeRegistry, Mode: "all"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported compression",
+			cache:   Cache{Type: CacheTypeRegistry, Compression: "bzip2"},
+			wantErr: true,
+		},
+		{
+			name:    "inline cache with max mode",
+			cache:   Cache{Type: CacheTypeInline, Mode: "max"},
+			wantErr: true,
+		},
+		{
+			name:    "local cache without directory",
+			cache:   Cache{Type: CacheTypeLocal},
+			wantErr: true,
+		},
+		{
+			name:    "s3 cache without bucket",
+			cache:   Cache{Type: CacheTypeS3, Options: []string{"region=eu-central-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid option",
+			cache:   Cache{Type: CacheTypeRegistry, Options: []string{"invalid"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cache.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCache)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestApplyCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		build         Build
+		branch        string
+		defaultBranch string
+		wantFrom      []string
+		wantTo        []string
+		wantErr       bool
+	}{
+		{
+			name: "registry cache with branch fallback",
+			build: Build{
+				Repo:  "example/repo",
+				Cache: Cache{Type: CacheTypeRegistry, Mode: "max", Auto: true},
+			},
+			branch:        "feature/Login",
+			defaultBranch: "main",
+			wantFrom: []string{
+				"type=registry,ref=example/repo:buildcache-feature-Login",
+				"type=registry,ref=example/repo:buildcache-main",
+			},
+			wantTo: []string{"type=registry,ref=example/repo:buildcache-feature-Login,mode=max"},
+		},
+		{
+			name: "registry cache on default branch",
+			build: Build{
+				Repo:  "example/repo",
+				Cache: Cache{Type: CacheTypeRegistry, Ref: "example/cache", Auto: true},
+			},
+			branch:        "main",
+			defaultBranch: "main",
+			wantFrom:      []string{"type=registry,ref=example/cache:buildcache-main"},
+			wantTo:        []string{"type=registry,ref=example/cache:buildcache-main"},
+		},
+		{
+			name: "registry cache with static ref",
+			build: Build{
+				CacheFrom: []string{"type=registry,ref=example/base:cache"},
+				Cache:     Cache{Type: CacheTypeRegistry, Ref: "example/repo:cache", Compression: "zstd"},
+			},
+			wantFrom: []string{
+				"type=registry,ref=example/base:cache",
+				"type=registry,ref=example/repo:cache",
+			},
+			wantTo: []string{"type=registry,ref=example/repo:cache,compression=zstd"},
+		},
+		{
+			name:    "registry cache without ref",
+			build:   Build{Cache: Cache{Type: CacheTypeRegistry, Auto: true}},
+			wantErr: true,
+		},
+		{
+			name:     "inline cache",
+			build:    Build{Cache: Cache{Type: CacheTypeInline, Ref: "example/repo:latest"}},
+			wantFrom: []string{"type=registry,ref=example/repo:latest"},
+			wantTo:   []string{"type=inline"},
+		},
+		{
+			name:          "local cache",
+			build:         Build{Cache: Cache{Type: CacheTypeLocal, Ref: "/cache/", Auto: true}},
+			branch:        "main",
+			defaultBranch: "main",
+			wantFrom:      []string{"type=local,src=/cache/buildcache-main"},
+			wantTo:        []string{"type=local,dest=/cache/buildcache-main"},
+		},
+		{
+			name: "s3 cache",
+			build: Build{
+				Cache: Cache{
+					Type:    CacheTypeS3,
+					Mode:    "max",
+					Auto:    true,
+					Options: []string{"bucket=cache", "region=eu-central-1"},
+				},
+			},
+			branch:        "dev",
+			defaultBranch: "main",
+			wantFrom: []string{
+				"type=s3,name=buildcache-dev,bucket=cache,region=eu-central-1",
+				"type=s3,name=buildcache-main,bucket=cache,region=eu-central-1",
+			},
+			wantTo: []string{"type=s3,name=buildcache-dev,mode=max,bucket=cache,region=eu-central-1"},
+		},
+		{
+			name: "append to cache-to",
+			build: Build{
+				CacheTo: []string{"type=local,dest=/cache"},
+				Cache:   Cache{Type: CacheTypeRegistry, Ref: "example/repo:cache"},
+			},
+			wantFrom: []string{"type=registry,ref=example/repo:cache"},
+			wantTo:   []string{"type=local,dest=/cache", "type=registry,ref=example/repo:cache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build.ApplyCache(tt.branch, tt.defaultBranch)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCache)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, tt.build.CacheFrom)
+			assert.Equal(t, tt.wantTo, tt.build.CacheTo)
+		})
+	}
+}
+
+func TestValidateCacheEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{
+			name:  "registry shorthand",
+			entry: "example/repo:cache",
+		},
+		{
+			name:  "registry cache",
+			entry: "type=registry,ref=example/repo:cache,mode=max",
+		},
+		{
+			name:  "inline cache",
+			entry: "type=inline",
+		},
+		{
+			name:  "local cache",
+			entry: "type=local,dest=/cache",
+		},
+		{
+			name:    "empty entry",
+			entry:   "",
+			wantErr: true,
+		},
+		{
+			name:    "missing type",
+			entry:   "ref=example/repo:cache",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			entry:   "type=ftp,dest=/cache",
+			wantErr: true,
+		},
+		{
+			name:    "registry cache without ref",
+			entry:   "type=registry,mode=max",
+			wantErr: true,
+		},
+		{
+			name:    "local cache without dest",
+			entry:   "type=local,src=/cache",
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			entry:   "type=registry,ref=example/repo:cache,mode=all",
+			wantErr: true,
+		},
+		{
+			name:    "invalid attribute",
+			entry:   "type=registry,ref",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCacheEntry(tt.entry)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCache)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestJoinCacheEntries(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragments []string
+		want      []string
+	}{
+		{
+			name:      "unescaped single entry",
+			fragments: []string{"type=registry", "ref=example/repo:cache", "mode=max"},
+			want:      []string{"type=registry,ref=example/repo:cache,mode=max"},
+		},
+		{
+			name:      "unescaped multiple entries",
+			fragments: []string{"type=registry", "ref=example/repo:cache", "type=local", "dest=/cache"},
+			want:      []string{"type=registry,ref=example/repo:cache", "type=local,dest=/cache"},
+		},
+		{
+			name:      "escaped entries",
+			fragments: []string{"type=registry,ref=example/repo:cache,mode=max", "type=local,dest=/cache"},
+			want:      []string{"type=registry,ref=example/repo:cache,mode=max", "type=local,dest=/cache"},
+		},
+		{
+			name:      "registry shorthand",
+			fragments: []string{"example/repo:cache", "type=inline"},
+			want:      []string{"example/repo:cache", "type=inline"},
+		},
+		{
+			name:      "empty",
+			fragments: []string{},
+			want:      []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, JoinCacheEntries(tt.fragments))
+		})
+	}
+}
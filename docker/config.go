@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	configDirPerm  = 0o700
+	configFilePerm = 0o600
+)
+
+var ErrInvalidConfig = errors.New("invalid docker config")
+
+// Config defines the docker CLI config file. Unknown keys are preserved.
+type Config struct {
+	Auths       map[string]AuthConfig  `json:"auths,omitempty"`
+	CredHelpers map[string]string      `json:"credHelpers,omitempty"`
+	CredsStore  string                 `json:"credsStore,omitempty"`
+	Proxies     map[string]ProxyConfig `json:"proxies,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+// AuthConfig defines the credentials of a registry in the docker config.
+type AuthConfig struct {
+	Auth          string `json:"auth,omitempty"`
+	Email         string `json:"email,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// ProxyConfig defines the proxy settings passed to containers in the docker config.
+type ProxyConfig struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+	FTPProxy   string `json:"ftpProxy,omitempty"`
+	AllProxy   string `json:"allProxy,omitempty"`
+}
+
+// NewConfig creates an empty docker config.
+func NewConfig() *Config {
+	c := &Config{}
+	c.init()
+
+	return c
+}
+
+// ParseConfig parses the content of a docker config file.
+func ParseConfig(data []byte) (*Config, error) {
+	c := NewConfig()
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return c, nil
+}
+
+// LoadConfig reads the docker config file from the given path. If the file does not exist,
+// an empty config is returned.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewConfig(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data)
+}
+
+// UnmarshalJSON implements json.Unmarshaler and keeps unknown keys.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type config Config
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var known config
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"auths", "credHelpers", "credsStore", "proxies"} {
+		delete(raw, key)
+	}
+
+	*c = Config(known)
+	c.extra = raw
+	c.init()
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler and writes unknown keys back.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	type config Config
+
+	data, err := json.Marshal((*config)(c))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.extra) == 0 {
+		return data, nil
+	}
+
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+
+	for key, value := range c.extra {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
+
+	return json.Marshal(merged)
+}
+
+// Merge merges the other config into the config. Values of the other config take precedence
+// for the same registry host, proxy name or unknown key.
+func (c *Config) Merge(other *Config) {
+	if other == nil {
+		return
+	}
+
+	c.init()
+
+	maps.Copy(c.Auths, other.Auths)
+	maps.Copy(c.CredHelpers, other.CredHelpers)
+	maps.Copy(c.Proxies, other.Proxies)
+	maps.Copy(c.extra, other.extra)
+
+	if other.CredsStore != "" {
+		c.CredsStore = other.CredsStore
+	}
+}
+
+// SetAuth sets the base64 encoded credentials of the registry host, like a docker login does.
+func (c *Config) SetAuth(host, username, password string) {
+	c.init()
+
+	c.Auths[host] = AuthConfig{
+		Auth: base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+}
+
+// Validate checks that all auth entries contain base64 encoded `username:password` credentials.
+func (c *Config) Validate() error {
+	for host, auth := range c.Auths {
+		if auth.Auth == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return fmt.Errorf("%w: auth of %s is not base64 encoded: %w", ErrInvalidConfig, host, err)
+		}
+
+		if username, _, ok := strings.Cut(string(decoded), ":"); !ok || username == "" {
+			return fmt.Errorf("%w: auth of %s must be in the format username:password", ErrInvalidConfig, host)
+		}
+	}
+
+	return nil
+}
+
+// helper function to initialize all nil maps.
+func (c *Config) init() {
+	if c.Auths == nil {
+		c.Auths = make(map[string]AuthConfig)
+	}
+
+	if c.CredHelpers == nil {
+		c.CredHelpers = make(map[string]string)
+	}
+
+	if c.Proxies == nil {
+		c.Proxies = make(map[string]ProxyConfig)
+	}
+
+	if c.extra == nil {
+		c.extra = make(map[string]json.RawMessage)
+	}
+}
+
+// Write writes the config to the given path with strict permissions.
+func (c *Config) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), configDirPerm); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return os.WriteFile(path, data, configFilePerm)
+}
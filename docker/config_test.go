@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := `{
+		"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNz"}},
+		"proxies": {"default": {"httpProxy": "http://proxy:3128", "noProxy": "localhost"}},
+		"currentContext": "default",
+		"plugins": {"buildx": {"enabled": "true"}}
+	}`
+
+	c, err := ParseConfig([]byte(data))
+	assert.NoError(t, err)
+	assert.Equal(t, AuthConfig{Auth: "dXNlcjpwYXNz"}, c.Auths["registry.example.com"])
+	assert.Equal(t, ProxyConfig{HTTPProxy: "http://proxy:3128", NoProxy: "localhost"}, c.Proxies["default"])
+
+	out, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, data, string(out))
+
+	_, err = ParseConfig([]byte(`{"auths": invalid}`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestConfigMerge(t *testing.T) {
+	base, err := ParseConfig([]byte(`{
+		"auths": {"a.example.com": {"auth": "YTpvbGQ="}, "b.example.com": {"auth": "YjpvbGQ="}},
+		"credHelpers": {"gcr.io": "gcloud"},
+		"credsStore": "desktop",
+		"proxies": {"default": {"httpProxy": "http://old:3128"}},
+		"psFormat": "old"
+	}`))
+	assert.NoError(t, err)
+
+	other, err := ParseConfig([]byte(`{
+		"auths": {"a.example.com": {"auth": "YTpuZXc="}},
+		"credHelpers": {"ghcr.io": "pass"},
+		"proxies": {"default": {"httpProxy": "http://new:3128"}},
+		"psFormat": "new"
+	}`))
+	assert.NoError(t, err)
+
+	base.Merge(other)
+
+	out, err := json.Marshal(base)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"auths": {"a.example.com": {"auth": "YTpuZXc="}, "b.example.com": {"auth": "YjpvbGQ="}},
+		"credHelpers": {"gcr.io": "gcloud", "ghcr.io": "pass"},
+		"credsStore": "desktop",
+		"proxies": {"default": {"httpProxy": "http://new:3128"}},
+		"psFormat": "new"
+	}`, string(out))
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auths   map[string]AuthConfig
+		wantErr bool
+	}{
+		{
+			name:  "valid auth",
+			auths: map[string]AuthConfig{"registry.example.com": {Auth: "dXNlcjpwYXNz"}},
+		},
+		{
+			name:  "identity token only",
+			auths: map[string]AuthConfig{"registry.example.com": {IdentityToken: "token"}},
+		},
+		{
+			name:    "invalid base64",
+			auths:   map[string]AuthConfig{"registry.example.com": {Auth: "not-base64!"}},
+			wantErr: true,
+		},
+		{
+			name:    "missing separator",
+			auths:   map[string]AuthConfig{"registry.example.com": {Auth: "dXNlcnBhc3M="}},
+			wantErr: true,
+		},
+		{
+			name:    "empty username",
+			auths:   map[string]AuthConfig{"registry.example.com": {Auth: "OnBhc3M="}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Auths: tt.auths}).Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestConfigWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".docker", "config.json")
+
+	c, err := LoadConfig(path)
+	assert.NoError(t, err)
+
+	c.CredHelpers["ghcr.io"] = "pass"
+	assert.NoError(t, c.Write(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ghcr.io": "pass"}, loaded.CredHelpers)
+}
+
+func TestConfigSetAuth(t *testing.T) {
+	conf := &Config{}
+	conf.SetAuth("quay.io", "octocat", "secret")
+
+	assert.Equal(t, map[string]AuthConfig{"quay.io": {Auth: "b2N0b2NhdDpzZWNyZXQ="}}, conf.Auths)
+	assert.NoError(t, conf.Validate())
+}
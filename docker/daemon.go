@@ -2,6 +2,7 @@ package docker
 
 import (
 	"os"
+	"time"
 
 	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
 )
@@ -10,71 +11,33 @@ const dockerdBin = "/usr/local/bin/dockerd"
 
 // Daemon defines Docker daemon parameters.
 type Daemon struct {
-	Registry             string   // Docker registry
-	Mirror               string   // Docker registry mirror
-	Insecure             bool     // Docker daemon enable insecure registries
-	StorageDriver        string   // Docker daemon storage driver
-	StoragePath          string   // Docker daemon storage path
-	Disabled             bool     // DOcker daemon is disabled (already running)
-	Debug                bool     // Docker daemon started in debug mode
-	Bip                  string   // Docker daemon network bridge IP address
-	DNS                  []string // Docker daemon dns server
-	DNSSearch            []string // Docker daemon dns search domain
-	MTU                  string   // Docker daemon mtu setting
-	IPv6                 bool     // Docker daemon IPv6 networking
-	Experimental         bool     // Docker daemon enable experimental mode
-	BuildkitConfigFile   string   // Docker buildkit config file
-	MaxConcurrentUploads string   // Docker daemon max concurrent uploads
+	Registry             string        // Docker registry
+	Mirrors              []string      // Docker registry mirrors
+	Insecure             bool          // Docker daemon enable insecure registries
+	StorageDriver        string        // Docker daemon storage driver
+	StoragePath          string        // Docker daemon storage path
+	Disabled             bool          // DOcker daemon is disabled (already running)
+	Debug                bool          // Docker daemon started in debug mode
+	Bip                  string        // Docker daemon network bridge IP address
+	DNS                  []string      // Docker daemon dns server
+	DNSSearch            []string      // Docker daemon dns search domain
+	MTU                  string        // Docker daemon mtu setting
+	IPv6                 bool          // Docker daemon IPv6 networking
+	Experimental         bool          // Docker daemon enable experimental mode
+	BuildkitConfigFile   string        // Docker buildkit config file
+	MaxConcurrentUploads string        // Docker daemon max concurrent uploads
+	StartTimeout         time.Duration // Docker daemon max time to wait until ready
+	LogLevel             string        // Docker daemon log level
+	DefaultAddressPools  []string      // Docker daemon default address pools
+	Features             []string      // Docker daemon features
+	CgroupDriver         string        // Docker daemon cgroup driver
+	ConfigOverride       string        // Docker daemon raw JSON config merged on top of the generated config
+	ConfigFile           string        // Docker daemon config file
 }
 
 // helper function to create the docker daemon command.
 func (d *Daemon) Start() *plugin_exec.Cmd {
-	args := []string{
-		"--data-root", d.StoragePath,
-		"--host=unix:///var/run/docker.sock",
-	}
-
-	if d.StorageDriver != "" {
-		args = append(args, "-s", d.StorageDriver)
-	}
-
-	if d.Insecure && d.Registry != "" {
-		args = append(args, "--insecure-registry", d.Registry)
-	}
-
-	if d.IPv6 {
-		args = append(args, "--ipv6")
-	}
-
-	if d.Mirror != "" {
-		args = append(args, "--registry-mirror", d.Mirror)
-	}
-
-	if d.Bip != "" {
-		args = append(args, "--bip", d.Bip)
-	}
-
-	for _, dns := range d.DNS {
-		args = append(args, "--dns", dns)
-	}
-
-	for _, dnsSearch := range d.DNSSearch {
-		args = append(args, "--dns-search", dnsSearch)
-	}
-
-	if d.MTU != "" {
-		args = append(args, "--mtu", d.MTU)
-	}
-
-	if d.Experimental {
-		args = append(args, "--experimental")
-	}
-
-	if d.MaxConcurrentUploads != "" {
-		args = append(args, "--max-concurrent-uploads", d.MaxConcurrentUploads)
-	}
-
-	cmd := plugin_exec.Command(dockerdBin, args...)
+	cmd := plugin_exec.Command(dockerdBin, "--config-file", d.ConfigFile)
 
 	if d.Debug {
 		cmd.Stdout = os.Stdout
@@ -0,0 +1,265 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidDaemonConfig = errors.New("invalid docker daemon config")
+
+	//nolint:gochecknoglobals
+	daemonLogLevels = []string{"debug", "info", "warn", "error", "fatal"}
+	//nolint:gochecknoglobals
+	daemonCgroupDrivers = []string{"cgroupfs", "systemd"}
+)
+
+// DaemonConfig defines the docker daemon config file (daemon.json). Only the keys generated from
+// the daemon settings are typed, other keys can be set with the raw config override.
+//
+//nolint:tagliatelle
+type DaemonConfig struct {
+	DataRoot             string          `json:"data-root,omitempty"`
+	Hosts                []string        `json:"hosts,omitempty"`
+	StorageDriver        string          `json:"storage-driver,omitempty"`
+	InsecureRegistries   []string        `json:"insecure-registries,omitempty"`
+	RegistryMirrors      []string        `json:"registry-mirrors,omitempty"`
+	IPv6                 bool            `json:"ipv6,omitempty"`
+	Bip                  string          `json:"bip,omitempty"`
+	DNS                  []string        `json:"dns,omitempty"`
+	DNSSearch            []string        `json:"dns-search,omitempty"`
+	MTU                  int             `json:"mtu,omitempty"`
+	Experimental         bool            `json:"experimental,omitempty"`
+	MaxConcurrentUploads int             `json:"max-concurrent-uploads,omitempty"`
+	LogLevel             string          `json:"log-level,omitempty"`
+	DefaultAddressPools  []AddressPool   `json:"default-address-pools,omitempty"`
+	Features             map[string]bool `json:"features,omitempty"`
+	ExecOpts             []string        `json:"exec-opts,omitempty"`
+}
+
+// AddressPool defines a default address pool of the docker daemon networks.
+type AddressPool struct {
+	Base string `json:"base"`
+	Size int    `json:"size"`
+}
+
+// Config creates the docker daemon config from the daemon settings.
+func (d *Daemon) Config() (*DaemonConfig, error) {
+	c := &DaemonConfig{
+		DataRoot:      d.StoragePath,
+		Hosts:         []string{"unix://" + DaemonSocket},
+		StorageDriver: d.StorageDriver,
+		IPv6:          d.IPv6,
+		Bip:           d.Bip,
+		DNS:           d.DNS,
+		DNSSearch:     d.DNSSearch,
+		Experimental:  d.Experimental,
+		LogLevel:      d.LogLevel,
+	}
+
+	if d.Insecure && d.Registry != "" {
+		c.InsecureRegistries = []string{d.Registry}
+	}
+
+	for _, mirror := range d.Mirrors {
+		if mirror != "" {
+			c.RegistryMirrors = append(c.RegistryMirrors, mirror)
+		}
+	}
+
+	var err error
+
+	if c.MTU, err = parseDaemonInt("mtu", d.MTU); err != nil {
+		return nil, err
+	}
+
+	if c.MaxConcurrentUploads, err = parseDaemonInt("max concurrent uploads", d.MaxConcurrentUploads); err != nil {
+		return nil, err
+	}
+
+	for _, pool := range d.DefaultAddressPools {
+		addressPool, err := ParseAddressPool(pool)
+		if err != nil {
+			return nil, err
+		}
+
+		c.DefaultAddressPools = append(c.DefaultAddressPools, addressPool)
+	}
+
+	for _, feature := range d.Features {
+		name, value, ok := strings.Cut(feature, "=")
+		if !ok {
+			value = "true"
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if name == "" || err != nil {
+			return nil, fmt.Errorf("%w: feature %s must be in the format name=true|false", ErrInvalidDaemonConfig, feature)
+		}
+
+		if c.Features == nil {
+			c.Features = make(map[string]bool)
+		}
+
+		c.Features[name] = enabled
+	}
+
+	if d.CgroupDriver != "" {
+		c.ExecOpts = []string{"native.cgroupdriver=" + d.CgroupDriver}
+	}
+
+	return c, nil
+}
+
+// GenerateConfig creates the content of the docker daemon config file. The raw config override
+// is merged on top of the config created from the daemon settings and the result is validated.
+func (d *Daemon) GenerateConfig() ([]byte, error) {
+	c, err := d.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDaemonConfig, err)
+	}
+
+	config := make(map[string]any)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDaemonConfig, err)
+	}
+
+	if strings.TrimSpace(d.ConfigOverride) != "" {
+		override := make(map[string]any)
+		if err := json.Unmarshal([]byte(d.ConfigOverride), &override); err != nil {
+			return nil, fmt.Errorf("%w: override must be a JSON object: %w", ErrInvalidDaemonConfig, err)
+		}
+
+		mergeDaemonConfig(config, override)
+	}
+
+	data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDaemonConfig, err)
+	}
+
+	parsed, err := ParseDaemonConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parsed.Validate(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ParseDaemonConfig parses the content of a docker daemon config file. Unknown keys are ignored.
+func ParseDaemonConfig(data []byte) (*DaemonConfig, error) {
+	c := &DaemonConfig{}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDaemonConfig, err)
+	}
+
+	return c, nil
+}
+
+// Validate checks the values of the docker daemon config.
+func (c *DaemonConfig) Validate() error {
+	if !slices.Contains(c.Hosts, "unix://"+DaemonSocket) {
+		return fmt.Errorf("%w: hosts must contain unix://%s", ErrInvalidDaemonConfig, DaemonSocket)
+	}
+
+	if c.LogLevel != "" && !slices.Contains(daemonLogLevels, c.LogLevel) {
+		return fmt.Errorf("%w: log level %s must be one of %s",
+			ErrInvalidDaemonConfig, c.LogLevel, strings.Join(daemonLogLevels, ", "))
+	}
+
+	for _, opt := range c.ExecOpts {
+		driver, ok := strings.CutPrefix(opt, "native.cgroupdriver=")
+		if ok && !slices.Contains(daemonCgroupDrivers, driver) {
+			return fmt.Errorf("%w: cgroup driver %s must be one of %s",
+				ErrInvalidDaemonConfig, driver, strings.Join(daemonCgroupDrivers, ", "))
+		}
+	}
+
+	for _, pool := range c.DefaultAddressPools {
+		if err := pool.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.MTU < 0 || c.MaxConcurrentUploads < 0 {
+		return fmt.Errorf("%w: mtu and max concurrent uploads must not be negative", ErrInvalidDaemonConfig)
+	}
+
+	return nil
+}
+
+// ParseAddressPool parses an address pool in the format `base:size`, e.g. `10.10.0.0/16:24`.
+func ParseAddressPool(s string) (AddressPool, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return AddressPool{}, fmt.Errorf("%w: address pool %s must be in the format base:size", ErrInvalidDaemonConfig, s)
+	}
+
+	size, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return AddressPool{}, fmt.Errorf("%w: address pool %s has an invalid size", ErrInvalidDaemonConfig, s)
+	}
+
+	pool := AddressPool{Base: s[:i], Size: size}
+
+	return pool, pool.Validate()
+}
+
+// Validate checks that the base is a network prefix and the size fits into it.
+func (a AddressPool) Validate() error {
+	prefix, err := netip.ParsePrefix(a.Base)
+	if err != nil {
+		return fmt.Errorf("%w: address pool base %s: %w", ErrInvalidDaemonConfig, a.Base, err)
+	}
+
+	if a.Size < prefix.Bits() || a.Size > prefix.Addr().BitLen() {
+		return fmt.Errorf("%w: address pool size %d must be between %d and %d",
+			ErrInvalidDaemonConfig, a.Size, prefix.Bits(), prefix.Addr().BitLen())
+	}
+
+	return nil
+}
+
+// helper function to parse an optional integer daemon setting.
+func parseDaemonInt(name, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%w: %s %s must be a non-negative number", ErrInvalidDaemonConfig, name, value)
+	}
+
+	return i, nil
+}
+
+// helper function to merge the override into the config. Nested objects are merged recursively,
+// all other values of the override replace the config values.
+func mergeDaemonConfig(config, override map[string]any) {
+	for key, value := range override {
+		nested, ok := value.(map[string]any)
+		if existing, isMap := config[key].(map[string]any); ok && isMap {
+			mergeDaemonConfig(existing, nested)
+
+			continue
+		}
+
+		config[key] = value
+	}
+}
@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonGenerateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		daemon  Daemon
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			daemon: Daemon{StoragePath: "/var/lib/docker"},
+			want:   `{"data-root": "/var/lib/docker", "hosts": ["unix:///var/run/docker.sock"]}`,
+		},
+		{
+			name: "settings",
+			daemon: Daemon{
+				StoragePath:          "/var/lib/docker",
+				Registry:             "registry.example.com",
+				Insecure:             true,
+				Mirrors:              []string{"https://mirror.example.com", "https://mirror.gcr.io"},
+				DNS:                  []string{"10.0.0.1"},
+				MTU:                  "1400",
+				MaxConcurrentUploads: "2",
+				LogLevel:             "warn",
+				DefaultAddressPools:  []string{"10.10.0.0/16:24"},
+				Features:             []string{"containerd-snapshotter=true", "buildkit"},
+				CgroupDriver:         "cgroupfs",
+			},
+			want: `{
+				"data-root": "/var/lib/docker",
+				"hosts": ["unix:///var/run/docker.sock"],
+				"insecure-registries": ["registry.example.com"],
+				"registry-mirrors": ["https://mirror.example.com", "https://mirror.gcr.io"],
+				"dns": ["10.0.0.1"],
+				"mtu": 1400,
+				"max-concurrent-uploads": 2,
+				"log-level": "warn",
+				"default-address-pools": [{"base": "10.10.0.0/16", "size": 24}],
+				"features": {"containerd-snapshotter": true, "buildkit": true},
+				"exec-opts": ["native.cgroupdriver=cgroupfs"]
+			}`,
+		},
+		{
+			name: "override",
+			daemon: Daemon{
+				StoragePath:    "/var/lib/docker",
+				LogLevel:       "warn",
+				Features:       []string{"containerd-snapshotter=true"},
+				ConfigOverride: `{"log-level": "error", "features": {"cdi": true}, "shutdown-timeout": 30}`,
+			},
+			want: `{
+				"data-root": "/var/lib/docker",
+				"hosts": ["unix:///var/run/docker.sock"],
+				"log-level": "error",
+				"features": {"containerd-snapshotter": true, "cdi": true},
+				"shutdown-timeout": 30
+			}`,
+		},
+		{
+			name:    "invalid mtu",
+			daemon:  Daemon{MTU: "large"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid log level",
+			daemon:  Daemon{LogLevel: "verbose"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cgroup driver",
+			daemon:  Daemon{CgroupDriver: "cgroupv2"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid feature",
+			daemon:  Daemon{Features: []string{"containerd-snapshotter=yes please"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid override",
+			daemon:  Daemon{ConfigOverride: `["debug"]`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid override type",
+			daemon:  Daemon{ConfigOverride: `{"mtu": "1400"}`},
+			wantErr: true,
+		},
+		{
+			name:    "override hosts without socket",
+			daemon:  Daemon{ConfigOverride: `{"hosts": ["tcp://0.0.0.0:2375"]}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.daemon.GenerateConfig()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDaemonConfig)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestParseAddressPool(t *testing.T) {
+	tests := []struct {
+		pool    string
+		want    AddressPool
+		wantErr bool
+	}{
+		{pool: "10.10.0.0/16:24", want: AddressPool{Base: "10.10.0.0/16", Size: 24}},
+		{pool: "fd00:1::/48:64", want: AddressPool{Base: "fd00:1::/48", Size: 64}},
+		{pool: "10.10.0.0/16", wantErr: true},
+		{pool: "10.10.0.0/16:8", wantErr: true},
+		{pool: "10.10.0.0/16:33", wantErr: true},
+		{pool: "10.10.0.0:24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pool, func(t *testing.T) {
+			got, err := ParseAddressPool(tt.pool)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDaemonConfig)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDaemonStart(t *testing.T) {
+	cmd := (&Daemon{ConfigFile: "/tmp/daemon.json"}).Start()
+	assert.Equal(t, []string{dockerdBin, "--config-file", "/tmp/daemon.json"}, cmd.Args)
+}
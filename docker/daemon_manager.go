@@ -0,0 +1,341 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+const (
+	DaemonSocket              = "/var/run/docker.sock"
+	DefaultDaemonStartTimeout = 60 * time.Second
+
+	daemonStopTimeout  = 10 * time.Second
+	daemonPollInterval = 250 * time.Millisecond
+	daemonPingTimeout  = time.Second
+	daemonLogSize      = 64 * 1024
+	daemonLogLines     = 100
+)
+
+var (
+	ErrDaemonExited  = errors.New("docker daemon exited")
+	ErrDaemonTimeout = errors.New("docker daemon not ready")
+)
+
+// DaemonManager manages the lifecycle of the docker daemon and the CoreDNS server. Processes
+// are terminated gracefully on Stop or when the context of the manager is canceled.
+type DaemonManager struct {
+	Socket       string        // Docker daemon unix socket
+	StartTimeout time.Duration // Max time to wait for the docker daemon to be ready
+	StopTimeout  time.Duration // Max time to wait for processes to exit before they are killed
+
+	mu        sync.Mutex
+	processes []*process
+	stopOnce  sync.Once
+	stopHook  func() bool
+}
+
+// process defines a managed process and its captured output.
+type process struct {
+	name   string
+	cmd    *plugin_exec.Cmd
+	output *ringBuffer
+	done   chan struct{}
+	err    error
+}
+
+// NewDaemonManager creates a daemon manager that stops all processes when the context is canceled.
+func NewDaemonManager(ctx context.Context, startTimeout time.Duration) *DaemonManager {
+	if startTimeout <= 0 {
+		startTimeout = DefaultDaemonStartTimeout
+	}
+
+	m := &DaemonManager{
+		Socket:       DaemonSocket,
+		StartTimeout: startTimeout,
+		StopTimeout:  daemonStopTimeout,
+	}
+
+	m.stopHook = context.AfterFunc(ctx, m.Stop)
+
+	return m
+}
+
+// StartCoreDNS starts the CoreDNS server in the background.
+func (m *DaemonManager) StartCoreDNS(cmd *plugin_exec.Cmd) error {
+	_, err := m.start("coredns", cmd)
+
+	return err
+}
+
+// StartDaemon starts the docker daemon and waits until its socket is ready. The output of the
+// daemon is available through DumpLogs if it exits early or is not ready in time.
+func (m *DaemonManager) StartDaemon(ctx context.Context, cmd *plugin_exec.Cmd) error {
+	proc, err := m.start("dockerd", cmd)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(m.StartTimeout)
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if m.ping(ctx) {
+			return nil
+		}
+
+		select {
+		case <-proc.done:
+			return fmt.Errorf("%w: %w", ErrDaemonExited, proc.err)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout.C:
+			return fmt.Errorf("%w after %s", ErrDaemonTimeout, m.StartTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitDaemon waits until the check command succeeds, e.g. for an external docker daemon that is
+// still starting. The output of the last failed check is returned with the timeout error.
+func WaitDaemon(ctx context.Context, timeout time.Duration, check func() *plugin_exec.Cmd) error {
+	if timeout <= 0 {
+		timeout = DefaultDaemonStartTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var output bytes.Buffer
+
+		cmd := check()
+		cmd.Stdout = io.Discard
+		cmd.Stderr = &output
+
+		err := cmd.Run()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w after %s: %w: %s", ErrDaemonTimeout, timeout, err, strings.TrimSpace(output.String()))
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop terminates all managed processes in reverse start order. Processes that do not exit
+// within the stop timeout are killed.
+func (m *DaemonManager) Stop() {
+	m.stopOnce.Do(func() {
+		if m.stopHook != nil {
+			m.stopHook()
+		}
+
+		m.mu.Lock()
+		processes := m.processes
+		m.mu.Unlock()
+
+		for i := len(processes) - 1; i >= 0; i-- {
+			processes[i].stop(m.StopTimeout)
+		}
+	})
+}
+
+// DumpLogs writes the last lines of the captured output of all managed processes to w.
+func (m *DaemonManager) DumpLogs(w io.Writer) {
+	m.mu.Lock()
+	processes := m.processes
+	m.mu.Unlock()
+
+	for _, proc := range processes {
+		lines := proc.output.Tail(daemonLogLines)
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "--- last %d lines of %s output ---\n", len(lines), proc.name)
+
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// start starts the command and captures its output in addition to the configured writers.
+func (m *DaemonManager) start(name string, cmd *plugin_exec.Cmd) (*process, error) {
+	proc := &process{
+		name:   name,
+		cmd:    cmd,
+		output: newRingBuffer(daemonLogSize),
+		done:   make(chan struct{}),
+	}
+
+	stdout := io.Writer(proc.output)
+	if cmd.Stdout != nil {
+		stdout = io.MultiWriter(cmd.Stdout, proc.output)
+	}
+
+	stderr := io.Writer(proc.output)
+	if cmd.Stderr != nil {
+		stderr = io.MultiWriter(cmd.Stderr, proc.output)
+	}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// do not wait for child processes that keep the output pipes open after the process exited
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = m.StopTimeout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting %s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	m.processes = append(m.processes, proc)
+	m.mu.Unlock()
+
+	go func() {
+		proc.err = cmd.Wait()
+		close(proc.done)
+	}()
+
+	return proc, nil
+}
+
+// ping checks whether the docker daemon API responds on the socket.
+func (m *DaemonManager) ping(ctx context.Context) bool {
+	client := &http.Client{
+		Timeout: daemonPingTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", m.Socket)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/_ping", nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// stop sends SIGTERM to the process and kills it if it does not exit within the timeout.
+func (p *process) stop(timeout time.Duration) {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = p.cmd.Process.Kill()
+	}
+
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+// ringBuffer keeps the last written bytes up to a fixed size. It is safe for concurrent use.
+type ringBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+// Write implements io.Writer. Data that exceeds the size of the buffer overwrites the oldest data.
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+
+	if len(p) >= len(b.buf) {
+		p = p[len(p)-len(b.buf):]
+	}
+
+	for len(p) > 0 {
+		written := copy(b.buf[b.pos:], p)
+		p = p[written:]
+		b.pos += written
+
+		if b.pos == len(b.buf) {
+			b.pos = 0
+			b.full = true
+		}
+	}
+
+	return n, nil
+}
+
+// Tail returns up to n of the last complete lines in the buffer.
+func (b *ringBuffer) Tail(n int) []string {
+	b.mu.Lock()
+
+	data := bytes.Clone(b.buf[:b.pos])
+
+	if b.full {
+		data = append(bytes.Clone(b.buf[b.pos:]), data...)
+
+		// drop the partially overwritten first line
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	b.mu.Unlock()
+
+	output := strings.TrimRight(string(data), "\r\n")
+	if output == "" {
+		return nil
+	}
+
+	lines := strings.Split(output, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
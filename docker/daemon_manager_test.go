@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+func newTestDaemonManager(t *testing.T, ctx context.Context) *DaemonManager {
+	t.Helper()
+
+	m := NewDaemonManager(ctx, 500*time.Millisecond)
+	m.Socket = filepath.Join(t.TempDir(), "docker.sock")
+	m.StopTimeout = time.Second
+
+	t.Cleanup(m.Stop)
+
+	return m
+}
+
+func TestDaemonManagerReady(t *testing.T) {
+	m := newTestDaemonManager(t, context.Background())
+
+	listener, err := net.Listen("unix", m.Socket)
+	assert.NoError(t, err)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("OK"))
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+
+	go func() { _ = server.Serve(listener) }()
+
+	t.Cleanup(func() { _ = server.Close() })
+
+	cmd := plugin_exec.Command("sleep", "30")
+	assert.NoError(t, m.StartDaemon(context.Background(), cmd))
+
+	m.Stop()
+	assert.NotNil(t, cmd.ProcessState)
+}
+
+func TestDaemonManagerExited(t *testing.T) {
+	m := newTestDaemonManager(t, context.Background())
+
+	cmd := plugin_exec.Command("sh", "-c", "echo 'failed to start daemon' >&2; exit 1")
+
+	err := m.StartDaemon(context.Background(), cmd)
+	assert.ErrorIs(t, err, ErrDaemonExited)
+
+	var logs bytes.Buffer
+
+	m.DumpLogs(&logs)
+	assert.Equal(t, "--- last 1 lines of dockerd output ---\nfailed to start daemon\n", logs.String())
+}
+
+func TestDaemonManagerTimeout(t *testing.T) {
+	m := newTestDaemonManager(t, context.Background())
+
+	err := m.StartDaemon(context.Background(), plugin_exec.Command("sh", "-c", "echo starting; sleep 30"))
+	assert.ErrorIs(t, err, ErrDaemonTimeout)
+
+	var logs bytes.Buffer
+
+	m.DumpLogs(&logs)
+	assert.Contains(t, logs.String(), "starting")
+}
+
+func TestDaemonManagerContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := newTestDaemonManager(t, ctx)
+
+	cmd := plugin_exec.Command("sleep", "30")
+	assert.NoError(t, m.StartCoreDNS(cmd))
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		select {
+		case <-m.processes[0].done:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRingBufferTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes []string
+		lines  int
+		want   []string
+	}{
+		{
+			name:   "empty",
+			size:   16,
+			writes: []string{},
+			lines:  10,
+			want:   nil,
+		},
+		{
+			name:   "not full",
+			size:   64,
+			writes: []string{"first\nsec", "ond\n"},
+			lines:  10,
+			want:   []string{"first", "second"},
+		},
+		{
+			name:   "line limit",
+			size:   64,
+			writes: []string{"first\nsecond\nthird\n"},
+			lines:  2,
+			want:   []string{"second", "third"},
+		},
+		{
+			name:   "overwritten",
+			size:   16,
+			writes: []string{"first\n", "second\n", "third\n"},
+			lines:  10,
+			want:   []string{"second", "third"},
+		},
+		{
+			name:   "write exceeds size",
+			size:   8,
+			writes: []string{"first\nsecond\nthird\n"},
+			lines:  10,
+			want:   []string{"third"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRingBuffer(tt.size)
+
+			for _, w := range tt.writes {
+				n, err := b.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+
+			assert.Equal(t, tt.want, b.Tail(tt.lines))
+		})
+	}
+}
+
+func TestWaitDaemon(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "ready")
+		check := func() *plugin_exec.Cmd {
+			return plugin_exec.Command("sh", "-c", "test -f "+marker+" || { touch "+marker+"; exit 1; }")
+		}
+
+		assert.NoError(t, WaitDaemon(context.Background(), 5*time.Second, check))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		check := func() *plugin_exec.Cmd {
+			return plugin_exec.Command("sh", "-c", "echo 'cannot connect to the docker daemon' >&2; exit 1")
+		}
+
+		err := WaitDaemon(context.Background(), 500*time.Millisecond, check)
+		assert.ErrorIs(t, err, ErrDaemonTimeout)
+		assert.Contains(t, err.Error(), "cannot connect to the docker daemon")
+	})
+}
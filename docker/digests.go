@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	digestDirPerm  = 0o755
+	digestFilePerm = 0o644
+)
+
+var ErrNoDigests = errors.New("no digests found")
+
+// Digest defines the image digest of a build pushed by digest only.
+type Digest struct {
+	Repo      string   `json:"repo"`
+	Digest    string   `json:"digest"`
+	Platforms []string `json:"platforms"`
+}
+
+// Source returns the image reference by digest.
+func (d Digest) Source() string {
+	return fmt.Sprintf("%s@%s", d.Repo, d.Digest)
+}
+
+// DigestOutput returns the buildx output to push the image by digest without tags.
+func (b *Build) DigestOutput() string {
+	return fmt.Sprintf("type=image,name=%s,push-by-digest=true,name-canonical=true,push=%t", b.Repo, !b.Dryrun)
+}
+
+// WriteDigest writes the digest to a file named after the digest in the given directory and
+// returns the path of the file.
+func WriteDigest(dir string, digest Digest) (string, error) {
+	if err := os.MkdirAll(dir, digestDirPerm); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(digest)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, strings.ReplaceAll(digest.Digest, ":", "-")+".json")
+
+	return path, os.WriteFile(path, data, digestFilePerm)
+}
+
+// ReadDigests reads all digest files from the given directory.
+func ReadDigests(dir string) ([]Digest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]Digest, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var digest Digest
+		if err := json.Unmarshal(data, &digest); err != nil {
+			return nil, fmt.Errorf("failed to parse digest file %s: %w", path, err)
+		}
+
+		if digest.Repo == "" || digest.Digest == "" {
+			return nil, fmt.Errorf("%w: incomplete digest file %s", ErrNoDigests, path)
+		}
+
+		digests = append(digests, digest)
+	}
+
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoDigests, dir)
+	}
+
+	return digests, nil
+}
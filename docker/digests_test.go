@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestOutput(t *testing.T) {
+	b := &Build{Repo: "quay.io/octocat/app"}
+	assert.Equal(t,
+		"type=image,name=quay.io/octocat/app,push-by-digest=true,name-canonical=true,push=true",
+		b.DigestOutput(),
+	)
+
+	b.Dryrun = true
+	assert.Equal(t,
+		"type=image,name=quay.io/octocat/app,push-by-digest=true,name-canonical=true,push=false",
+		b.DigestOutput(),
+	)
+}
+
+func TestWriteReadDigests(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "digests")
+
+	_, err := ReadDigests(dir)
+	assert.ErrorIs(t, err, ErrNoDigests)
+
+	amd64 := Digest{Repo: "quay.io/octocat/app", Digest: "sha256:aaa", Platforms: []string{"linux/amd64"}}
+	arm64 := Digest{Repo: "quay.io/octocat/app", Digest: "sha256:bbb", Platforms: []string{"linux/arm64"}}
+
+	path, err := WriteDigest(dir, amd64)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "sha256-aaa.json"), path)
+
+	_, err = WriteDigest(dir, arm64)
+	assert.NoError(t, err)
+
+	digests, err := ReadDigests(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []Digest{amd64, arm64}, digests)
+	assert.Equal(t, "quay.io/octocat/app@sha256:aaa", digests[0].Source())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"repo": ""}`), 0o600))
+
+	_, err = ReadDigests(dir)
+	assert.ErrorIs(t, err, ErrNoDigests)
+}
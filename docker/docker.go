@@ -18,10 +18,12 @@ type Registry struct {
 	Password string // Docker registry password
 	Email    string // Docker registry email
 	Config   string // Docker Auth Config
+	Repo     string // Docker registry repository for additional image tags
 }
 
 // Build defines Docker build parameters.
 type Build struct {
+	Name          string            // Docker build name
 	Ref           string            // Git commit ref
 	Branch        string            // Git repository branch
 	Containerfile string            // Docker build Containerfile
@@ -36,7 +38,8 @@ type Build struct {
 	Target        string            // Docker build target
 	Pull          bool              // Docker build pull
 	CacheFrom     []string          // Docker build cache-from
-	CacheTo       string            // Docker build cache-to
+	CacheTo       []string          // Docker build cache-to
+	Cache         Cache             // Docker build structured cache
 	Compress      bool              // Docker build compress
 	Repo          string            // Docker build repository
 	NoCache       bool              // Docker build no-cache
@@ -51,6 +54,7 @@ type Build struct {
 	Secrets       []string          // Docker build secrets
 	Dryrun        bool              // Docker build dryrun
 	Time          string            // Docker build time
+	MetadataFile  string            // Docker build metadata file
 }
 
 // helper function to create the docker login command.
@@ -58,7 +62,7 @@ func (r *Registry) Login() *plugin_exec.Cmd {
 	args := []string{
 		"login",
 		"-u", r.Username,
-		"-p", r.Password,
+		"--password-stdin",
 	}
 
 	if r.Email != "" {
@@ -68,6 +72,16 @@ func (r *Registry) Login() *plugin_exec.Cmd {
 	args = append(args, r.Address)
 
 	cmd := plugin_exec.Command(dockerBin, args...)
+	cmd.Stdin = strings.NewReader(r.Password)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
+
+// helper function to create the docker logout command.
+func (r *Registry) Logout() *plugin_exec.Cmd {
+	cmd := plugin_exec.Command(dockerBin, "logout", r.Address)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -128,8 +142,8 @@ func (b *Build) Run(env []string) *plugin_exec.Cmd {
 		args = append(args, "--cache-from", arg)
 	}
 
-	if b.CacheTo != "" {
-		args = append(args, "--cache-to", b.CacheTo)
+	for _, arg := range b.CacheTo {
+		args = append(args, "--cache-to", arg)
 	}
 
 	for _, arg := range b.ArgsEnv {
@@ -164,12 +178,8 @@ func (b *Build) Run(env []string) *plugin_exec.Cmd {
 		args = append(args, "--platform", strings.Join(b.Platforms, ","))
 	}
 
-	for _, arg := range b.Tags {
-		args = append(args, "-t", fmt.Sprintf("%s:%s", b.Repo, arg))
-	}
-
-	for _, arg := range b.ExtraTags {
-		args = append(args, "-t", arg)
+	for _, ref := range b.Refs() {
+		args = append(args, "-t", ref)
 	}
 
 	for _, arg := range b.Labels {
@@ -188,6 +198,10 @@ func (b *Build) Run(env []string) *plugin_exec.Cmd {
 		args = append(args, "--secret", secret)
 	}
 
+	if b.MetadataFile != "" {
+		args = append(args, "--metadata-file", b.MetadataFile)
+	}
+
 	cmd := plugin_exec.Command(dockerBin, args...)
 
 	cmd.Env = append(os.Environ(), env...)
@@ -197,6 +211,17 @@ func (b *Build) Run(env []string) *plugin_exec.Cmd {
 	return cmd
 }
 
+// Refs returns the fully qualified image references of all build tags.
+func (b *Build) Refs() []string {
+	refs := make([]string, 0, len(b.Tags)+len(b.ExtraTags))
+
+	for _, tag := range b.Tags {
+		refs = append(refs, fmt.Sprintf("%s:%s", b.Repo, tag))
+	}
+
+	return append(refs, b.ExtraTags...)
+}
+
 // helper function to add proxy values from the environment.
 func (b *Build) AddProxyBuildArgs() {
 	b.addProxyValue("http_proxy")
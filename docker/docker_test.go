@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -195,3 +196,58 @@ func TestAddArgFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestRefs(t *testing.T) {
+	tests := []struct {
+		name  string
+		build Build
+		want  []string
+	}{
+		{
+			name: "tags and extra tags",
+			build: Build{
+				Repo:      "example/repo",
+				Tags:      []string{"latest", "v1"},
+				ExtraTags: []string{"ghcr.io/example/repo:latest"},
+			},
+			want: []string{"example/repo:latest", "example/repo:v1", "ghcr.io/example/repo:latest"},
+		},
+		{
+			name:  "no tags",
+			build: Build{Repo: "example/repo"},
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.build.Refs())
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	r := &Registry{
+		Address:  "quay.io",
+		Username: "octocat",
+		Password: "secret",
+	}
+
+	cmd := r.Login()
+	assert.Equal(t, []string{dockerBin, "login", "-u", "octocat", "--password-stdin", "quay.io"}, cmd.Args)
+
+	stdin, err := io.ReadAll(cmd.Stdin)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(stdin))
+}
+
+func TestLogout(t *testing.T) {
+	r := &Registry{
+		Address:  "quay.io",
+		Username: "octocat",
+		Password: "secret",
+	}
+
+	cmd := r.Logout()
+	assert.Equal(t, []string{dockerBin, "logout", "quay.io"}, cmd.Args)
+}
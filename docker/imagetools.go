@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+// Imagetools defines Docker buildx imagetools parameters.
+type Imagetools struct {
+	Sources []string // Source images or digests
+	Refs    []string // Target image references
+	Dryrun  bool     // Print the resulting manifest without pushing
+}
+
+// helper function to create the docker buildx imagetools create command.
+func (i *Imagetools) Create() *plugin_exec.Cmd {
+	args := []string{
+		"buildx",
+		"imagetools",
+		"create",
+	}
+
+	if i.Dryrun {
+		args = append(args, "--dry-run")
+	}
+
+	for _, ref := range i.Refs {
+		args = append(args, "-t", ref)
+	}
+
+	args = append(args, i.Sources...)
+
+	cmd := plugin_exec.Command(dockerBin, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd
+}
+
+// ManifestDigest returns the digest of the manifest printed by a dry run of the create command.
+func ManifestDigest(manifest []byte) string {
+	sum := sha256.Sum256(bytes.TrimSuffix(manifest, []byte("\n")))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
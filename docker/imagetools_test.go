@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImagetoolsCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		imagetools Imagetools
+		want       []string
+	}{
+		{
+			name: "promote tag",
+			imagetools: Imagetools{
+				Sources: []string{"quay.io/octocat/app:sha-abc"},
+				Refs:    []string{"quay.io/octocat/app:1.2.3", "ghcr.io/octocat/app:latest"},
+			},
+			want: []string{
+				dockerBin, "buildx", "imagetools", "create",
+				"-t", "quay.io/octocat/app:1.2.3",
+				"-t", "ghcr.io/octocat/app:latest",
+				"quay.io/octocat/app:sha-abc",
+			},
+		},
+		{
+			name: "dry run",
+			imagetools: Imagetools{
+				Sources: []string{"quay.io/octocat/app@sha256:abc"},
+				Refs:    []string{"quay.io/octocat/app:latest"},
+				Dryrun:  true,
+			},
+			want: []string{
+				dockerBin, "buildx", "imagetools", "create", "--dry-run",
+				"-t", "quay.io/octocat/app:latest",
+				"quay.io/octocat/app@sha256:abc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.imagetools.Create().Args)
+		})
+	}
+}
+
+func TestManifestDigest(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	want := "sha256:bafebd36189ad3688b7b3915ea55d461e0bfcfbdde11e54b0a123999fb6be50f"
+
+	assert.Equal(t, want, ManifestDigest(manifest))
+	assert.Equal(t, want, ManifestDigest(append(manifest, '\n')))
+}
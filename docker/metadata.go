@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Metadata defines the build result written by buildx to the metadata file.
+//
+//nolint:tagliatelle
+type Metadata struct {
+	Digest    string `json:"containerimage.digest"` // Image manifest digest
+	ImageName string `json:"image.name"`            // Comma separated list of image names
+}
+
+// Result defines the machine-readable result of an image build.
+type Result struct {
+	Name      string   `json:"name,omitempty"`
+	Digest    string   `json:"digest"`
+	Tags      []string `json:"tags"`
+	Platforms []string `json:"platforms"`
+}
+
+// ReadMetadata reads the metadata file of a build run.
+func ReadMetadata(path string) (Metadata, error) {
+	var meta Metadata
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("failed to parse build metadata: %w", err)
+	}
+
+	return meta, nil
+}
+
+// ReadBakeMetadata reads the metadata file of a bake run and returns the results by target name.
+func ReadBakeMetadata(path string) (map[string]Metadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse bake metadata: %w", err)
+	}
+
+	result := make(map[string]Metadata, len(raw))
+
+	for target, value := range raw {
+		// skip buildx internal entries like build warnings
+		if strings.HasPrefix(target, "buildx.") {
+			continue
+		}
+
+		var meta Metadata
+		if err := json.Unmarshal(value, &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse bake metadata for target %s: %w", target, err)
+		}
+
+		result[target] = meta
+	}
+
+	return result, nil
+}
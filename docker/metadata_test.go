@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Metadata
+		wantErr bool
+	}{
+		{
+			name: "image digest",
+			content: `{
+				"buildx.build.ref": "builder/builder0/abc",
+				"containerimage.digest": "sha256:aaa",
+				"image.name": "example/repo:latest,example/repo:v1"
+			}`,
+			want: Metadata{Digest: "sha256:aaa", ImageName: "example/repo:latest,example/repo:v1"},
+		},
+		{
+			name:    "empty metadata",
+			content: `{}`,
+			want:    Metadata{},
+		},
+		{
+			name:    "invalid json",
+			content: `invalid`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metadata.json")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := ReadMetadata(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadBakeMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]Metadata
+		wantErr bool
+	}{
+		{
+			name: "multiple targets",
+			content: `{
+				"buildx.build.warnings": [],
+				"api": {"containerimage.digest": "sha256:aaa", "image.name": "example/api:latest"},
+				"web": {"containerimage.digest": "sha256:bbb", "image.name": "example/web:latest"}
+			}`,
+			want: map[string]Metadata{
+				"api": {Digest: "sha256:aaa", ImageName: "example/api:latest"},
+				"web": {Digest: "sha256:bbb", ImageName: "example/web:latest"},
+			},
+		},
+		{
+			name:    "invalid json",
+			content: `{"api": invalid}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metadata.json")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := ReadBakeMetadata(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
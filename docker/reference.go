@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxTagLength    = 128
+	maxNameLength   = 255
+	domainComponent = `(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)`
+)
+
+var (
+	ErrInvalidReference  = errors.New("invalid reference")
+	ErrInvalidTag        = errors.New("invalid tag")
+	ErrInvalidRepository = errors.New("invalid repository")
+	ErrInvalidDigest     = errors.New("invalid digest")
+
+	tagInvalidChars      = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	tagPattern           = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*$`)
+	domainPattern        = regexp.MustCompile(`^` + domainComponent + `(?:\.` + domainComponent + `)*(?::[0-9]+)?$`)
+	pathComponentPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	pathInvalidChars     = regexp.MustCompile(`[^a-z0-9._-]+`)
+	pathSeparatorRuns    = regexp.MustCompile(`[._-]{2,}`)
+	digestPattern        = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+)
+
+// SanitizeTag replaces all characters that are not allowed in an image tag and truncates
+// the tag to the max allowed length.
+func SanitizeTag(tag string) string {
+	tag = tagInvalidChars.ReplaceAllString(tag, "-")
+	tag = strings.TrimLeft(tag, ".-")
+
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+
+	return tag
+}
+
+// SanitizeRepository converts the repository path to lowercase and replaces all characters
+// that are not allowed in a repository path component. The registry domain is kept as it is.
+func SanitizeRepository(repo string) string {
+	domain, path := splitDomain(repo)
+
+	components := strings.Split(path, "/")
+	for i, component := range components {
+		component = pathInvalidChars.ReplaceAllString(strings.ToLower(component), "-")
+		component = pathSeparatorRuns.ReplaceAllStringFunc(component, func(s string) string {
+			// only dashes and double underscores may be repeated
+			if s == "__" || strings.Trim(s, "-") == "" {
+				return s
+			}
+
+			return "-"
+		})
+		components[i] = strings.Trim(component, "._-")
+	}
+
+	path = strings.Join(components, "/")
+
+	if domain != "" {
+		return domain + "/" + path
+	}
+
+	return path
+}
+
+// SanitizeReference sanitizes the repository and the tag of an image reference.
+func SanitizeReference(ref string) string {
+	name, tag, ok := SplitReference(ref)
+	if !ok {
+		return SanitizeRepository(name)
+	}
+
+	return SanitizeRepository(name) + ":" + SanitizeTag(tag)
+}
+
+// ValidateTag checks the tag against the image tag grammar.
+func ValidateTag(tag string) error {
+	if len(tag) > maxTagLength {
+		return fmt.Errorf("%w: %q exceeds %d characters", ErrInvalidTag, tag, maxTagLength)
+	}
+
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("%w: %q may only contain [a-zA-Z0-9_.-] and must not start with '.' or '-'", ErrInvalidTag, tag)
+	}
+
+	return nil
+}
+
+// ValidateRepository checks the repository name against the image name grammar.
+func ValidateRepository(repo string) error {
+	if repo == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidRepository)
+	}
+
+	if len(repo) > maxNameLength {
+		return fmt.Errorf("%w: %q exceeds %d characters", ErrInvalidRepository, repo, maxNameLength)
+	}
+
+	domain, path := splitDomain(repo)
+	if domain != "" && !domainPattern.MatchString(domain) {
+		return fmt.Errorf("%w: %q has an invalid registry domain", ErrInvalidRepository, repo)
+	}
+
+	for _, component := range strings.Split(path, "/") {
+		if !pathComponentPattern.MatchString(component) {
+			return fmt.Errorf(
+				"%w: %q must be lowercase and may only contain [a-z0-9] separated by '.', '_' or '-'",
+				ErrInvalidRepository, repo,
+			)
+		}
+	}
+
+	return nil
+}
+
+// ValidateReference checks an image reference in the form repository[:tag][@digest].
+func ValidateReference(ref string) error {
+	ref, digest, hasDigest := strings.Cut(ref, "@")
+	if hasDigest && !digestPattern.MatchString(digest) {
+		return fmt.Errorf("%w: %q", ErrInvalidDigest, digest)
+	}
+
+	name, tag, ok := SplitReference(ref)
+	if err := ValidateRepository(name); err != nil {
+		return err
+	}
+
+	if ok {
+		return ValidateTag(tag)
+	}
+
+	return nil
+}
+
+// ValidateRefs validates the repository, tags and extra tags of the build and returns the
+// joined errors of all invalid references.
+func (b *Build) ValidateRefs() error {
+	errs := make([]error, 0)
+
+	if b.Repo != "" {
+		if err := ValidateRepository(b.Repo); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, tag := range b.Tags {
+		if err := ValidateTag(tag); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, ref := range b.ExtraTags {
+		if err := ValidateReference(ref); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// splitDomain splits the registry domain from the repository path. The first component is
+// treated as domain if it contains a '.' or ':' or is 'localhost', like the docker CLI does.
+func splitDomain(repo string) (string, string) {
+	domain, path, ok := strings.Cut(repo, "/")
+	if !ok || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		return "", repo
+	}
+
+	return domain, path
+}
+
+// SplitReference splits the tag from an image reference. A colon within the registry domain,
+// e.g. a port, is not treated as tag separator.
+func SplitReference(ref string) (string, string, bool) {
+	domain, path := splitDomain(ref)
+
+	name, tag, ok := strings.Cut(path, ":")
+	if domain != "" {
+		name = domain + "/" + name
+	}
+
+	return name, tag, ok
+}
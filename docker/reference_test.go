@@ -0,0 +1,188 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{
+			name: "valid tag",
+			tag:  "v1.0.0_rc.1",
+			want: "v1.0.0_rc.1",
+		},
+		{
+			name: "branch with slash",
+			tag:  "feature/login",
+			want: "feature-login",
+		},
+		{
+			name: "leading separator",
+			tag:  ".-dev",
+			want: "dev",
+		},
+		{
+			name: "too long",
+			tag:  strings.Repeat("a", 200),
+			want: strings.Repeat("a", 128),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeTag(tt.tag))
+		})
+	}
+}
+
+func TestSanitizeRepository(t *testing.T) {
+	tests := []struct {
+		name string
+		repo string
+		want string
+	}{
+		{
+			name: "valid repository",
+			repo: "quay.io/octocat/app",
+			want: "quay.io/octocat/app",
+		},
+		{
+			name: "uppercase path",
+			repo: "OctoCat/My_App",
+			want: "octocat/my_app",
+		},
+		{
+			name: "keep registry domain with port",
+			repo: "Registry.local:5000/OctoCat/App",
+			want: "Registry.local:5000/octocat/app",
+		},
+		{
+			name: "invalid characters and separators",
+			repo: "octocat/my app!/.-web-.",
+			want: "octocat/my-app/web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeRepository(tt.repo)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, ValidateRepository(got))
+		})
+	}
+}
+
+func TestSanitizeReference(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{
+			name: "repository and tag",
+			ref:  "quay.io/OctoCat/app:feature/login",
+			want: "quay.io/octocat/app:feature-login",
+		},
+		{
+			name: "registry port without tag",
+			ref:  "localhost:5000/OctoCat/app",
+			want: "localhost:5000/octocat/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeReference(tt.ref))
+		})
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr bool
+	}{
+		{name: "valid tag", tag: "v1.0.0_rc.1"},
+		{name: "slash", tag: "feature/login", wantErr: true},
+		{name: "leading dash", tag: "-dev", wantErr: true},
+		{name: "empty", tag: "", wantErr: true},
+		{name: "too long", tag: strings.Repeat("a", 129), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTag(tt.tag)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTag)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr error
+	}{
+		{name: "docker hub", ref: "octocat/app:latest"},
+		{name: "registry with port", ref: "registry.local:5000/octocat/app"},
+		{name: "localhost", ref: "localhost/app:1.0"},
+		{name: "separators", ref: "octocat/my__app-web.v2:latest"},
+		{name: "digest", ref: "octocat/app@sha256:" + strings.Repeat("a", 64)},
+		{name: "tag and digest", ref: "octocat/app:1.0@sha256:" + strings.Repeat("a", 64)},
+		{name: "invalid digest", ref: "octocat/app@sha256:abc", wantErr: ErrInvalidDigest},
+		{name: "uppercase", ref: "OctoCat/app:latest", wantErr: ErrInvalidRepository},
+		{name: "invalid domain", ref: "-quay.io/octocat/app", wantErr: ErrInvalidRepository},
+		{name: "empty path component", ref: "octocat//app", wantErr: ErrInvalidRepository},
+		{name: "invalid tag", ref: "octocat/app:.dev", wantErr: ErrInvalidTag},
+		{name: "too long", ref: strings.Repeat("a", 256), wantErr: ErrInvalidRepository},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateReference(tt.ref)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBuildValidateRefs(t *testing.T) {
+	b := &Build{
+		Repo:      "OctoCat/app",
+		Tags:      []string{"latest", "feature/login"},
+		ExtraTags: []string{"quay.io/octocat/app:latest", "quay.io/octocat/app:-dev"},
+	}
+
+	err := b.ValidateRefs()
+	assert.ErrorIs(t, err, ErrInvalidRepository)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	assert.Contains(t, err.Error(), "OctoCat/app")
+	assert.Contains(t, err.Error(), "feature/login")
+	assert.Contains(t, err.Error(), "-dev")
+
+	b = &Build{
+		Repo:      "octocat/app",
+		Tags:      []string{"latest"},
+		ExtraTags: []string{"quay.io/octocat/app:latest"},
+	}
+	assert.NoError(t, b.ValidateRefs())
+}
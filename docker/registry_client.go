@@ -0,0 +1,268 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+	dockerHubLibrary  = "library/"
+
+	digestHeader = "Docker-Content-Digest"
+)
+
+var (
+	ErrRegistryRequest  = errors.New("registry request failed")
+	ErrManifestNotFound = errors.New("manifest not found")
+
+	challengeParams = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+	dockerHubAliases = []string{dockerHubDomain, "index.docker.io", dockerHubRegistry}
+
+	manifestMediaTypes = []string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}
+)
+
+// RegistryClient is a minimal client for the registry HTTP API. It supports anonymous, basic
+// and bearer token authentication.
+type RegistryClient struct {
+	HTTPClient *http.Client
+	Scheme     string
+	Username   string
+	Password   string
+}
+
+// NewRegistryClient creates a registry client with the given credentials.
+func NewRegistryClient(username, password string) *RegistryClient {
+	return &RegistryClient{
+		HTTPClient: http.DefaultClient,
+		Scheme:     "https",
+		Username:   username,
+		Password:   password,
+	}
+}
+
+// NormalizeRegistry returns the host of a registry address, e.g. https://index.docker.io/v1/
+// becomes docker.io.
+func NormalizeRegistry(address string) string {
+	host := address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	host, _, _ = strings.Cut(host, "/")
+
+	for _, alias := range dockerHubAliases {
+		if host == alias {
+			return dockerHubDomain
+		}
+	}
+
+	return host
+}
+
+// ParseRepository returns the registry host and the repository path of an image repository.
+// Docker Hub repositories are resolved to the registry API host and the library namespace.
+func ParseRepository(repo string) (string, string) {
+	domain, path := splitDomain(repo)
+	if domain == "" || NormalizeRegistry(domain) == dockerHubDomain {
+		if !strings.Contains(path, "/") {
+			path = dockerHubLibrary + path
+		}
+
+		return dockerHubRegistry, path
+	}
+
+	return domain, path
+}
+
+// Digest returns the manifest digest of the tag in the repository. If the tag does not exist,
+// ErrManifestNotFound is returned.
+func (c *RegistryClient) Digest(ctx context.Context, repo, tag string) (string, error) {
+	resp, err := c.do(ctx, http.MethodHead, repo, "manifests/"+tag, "pull")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, repo, tag); err != nil {
+		return "", err
+	}
+
+	digest := resp.Header.Get(digestHeader)
+	if digest == "" {
+		return "", fmt.Errorf("%w: no digest returned for %s:%s", ErrRegistryRequest, repo, tag)
+	}
+
+	return digest, nil
+}
+
+// DeleteTag deletes the tag from the repository. Other tags of the same manifest are kept. The
+// registry must support tag deletion as defined by the OCI distribution spec. If the tag does not
+// exist, ErrManifestNotFound is returned.
+func (c *RegistryClient) DeleteTag(ctx context.Context, repo, tag string) error {
+	resp, err := c.do(ctx, http.MethodDelete, repo, "manifests/"+tag, "pull,push,delete")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp, repo, tag)
+}
+
+// do sends a request to the registry API of the repository and handles the authentication
+// challenge of the registry.
+func (c *RegistryClient) do(ctx context.Context, method, repo, path, actions string) (*http.Response, error) {
+	host, name := ParseRepository(repo)
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/%s", c.Scheme, host, name, path)
+
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRegistryRequest, err)
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+
+	if req, err = newRequest(); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		req.SetBasicAuth(c.Username, c.Password)
+	case "bearer":
+		token, err := c.token(ctx, params["realm"], params["service"], fmt.Sprintf("repository:%s:%s", name, actions))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return nil, fmt.Errorf("%w: unsupported authentication scheme %q", ErrRegistryRequest, scheme)
+	}
+
+	resp, err = c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRegistryRequest, err)
+	}
+
+	return resp, nil
+}
+
+// token requests a bearer token for the scope from the token service of the registry.
+func (c *RegistryClient) token(ctx context.Context, realm, service, scope string) (string, error) {
+	if realm == "" {
+		return "", fmt.Errorf("%w: missing token realm", ErrRegistryRequest)
+	}
+
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid token realm: %w", ErrRegistryRequest, err)
+	}
+
+	query := u.Query()
+	if service != "" {
+		query.Set("service", service)
+	}
+
+	query.Set("scope", scope)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrRegistryRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: token request returned %s", ErrRegistryRequest, resp.Status)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"` //nolint:tagliatelle
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("%w: invalid token response: %w", ErrRegistryRequest, err)
+	}
+
+	if result.Token != "" {
+		return result.Token, nil
+	}
+
+	if result.AccessToken != "" {
+		return result.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("%w: empty token response", ErrRegistryRequest)
+}
+
+// parseChallenge parses the authentication scheme and parameters of a WWW-Authenticate header.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for _, match := range challengeParams.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+
+	return scheme, params
+}
+
+// checkResponse maps the response status of a manifest request to an error.
+func checkResponse(resp *http.Response, repo, tag string) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s:%s", ErrManifestNotFound, repo, tag)
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s:%s returned %s", ErrRegistryRequest, repo, tag, resp.Status)
+	}
+}
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"https://index.docker.io/v1/", "docker.io"},
+		{"docker.io", "docker.io"},
+		{"quay.io", "quay.io"},
+		{"https://registry.local:5000", "registry.local:5000"},
+		{"registry.local:5000/v2/", "registry.local:5000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeRegistry(tt.address))
+		})
+	}
+}
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		repo     string
+		wantHost string
+		wantPath string
+	}{
+		{"alpine", "registry-1.docker.io", "library/alpine"},
+		{"octocat/app", "registry-1.docker.io", "octocat/app"},
+		{"docker.io/octocat/app", "registry-1.docker.io", "octocat/app"},
+		{"quay.io/octocat/app", "quay.io", "octocat/app"},
+		{"localhost:5000/app", "localhost:5000", "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			host, path := ParseRepository(tt.repo)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
+
+func newTestRegistry(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+
+	tags := map[string]string{"pr-1": "sha256:abc"}
+
+	var server *httptest.Server
+
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "octocat" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+
+			assert.Equal(t, "registry", r.URL.Query().Get("service"))
+			assert.True(t, strings.HasPrefix(r.URL.Query().Get("scope"), "repository:octocat/app:"))
+
+			_, _ = w.Write([]byte(`{"token": "valid-token"}`))
+
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		tag := strings.TrimPrefix(r.URL.Path, "/v2/octocat/app/manifests/")
+
+		digest, ok := tags[tag]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set(digestHeader, digest)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			delete(tags, tag)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, tags
+}
+
+func TestRegistryClient(t *testing.T) {
+	server, tags := newTestRegistry(t)
+	repo := strings.TrimPrefix(server.URL, "https://") + "/octocat/app"
+
+	client := NewRegistryClient("octocat", "secret")
+	client.HTTPClient = server.Client()
+
+	ctx := context.Background()
+
+	digest, err := client.Digest(ctx, repo, "pr-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", digest)
+
+	_, err = client.Digest(ctx, repo, "pr-2")
+	assert.ErrorIs(t, err, ErrManifestNotFound)
+
+	assert.NoError(t, client.DeleteTag(ctx, repo, "pr-1"))
+	assert.NotContains(t, tags, "pr-1")
+	assert.ErrorIs(t, client.DeleteTag(ctx, repo, "pr-1"), ErrManifestNotFound)
+
+	client = NewRegistryClient("octocat", "wrong")
+	client.HTTPClient = server.Client()
+
+	_, err = client.Digest(ctx, repo, "pr-1")
+	assert.ErrorIs(t, err, ErrRegistryRequest)
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(
+		`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`,
+	)
+
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/alpine:pull",
+	}, params)
+}
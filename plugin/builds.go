@@ -0,0 +1,279 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_file "github.com/thegeeklab/wp-plugin-go/v6/file"
+)
+
+var (
+	ErrInvalidBuilds = errors.New("invalid build definitions")
+	ErrBuildsFailed  = errors.New("builds failed")
+)
+
+// BuildDefinition defines a single image build of the build matrix. Fields that are not set
+// are inherited from the plugin build settings.
+//
+//nolint:tagliatelle
+type BuildDefinition struct {
+	Name          string            `json:"name"`
+	Containerfile string            `json:"containerfile"`
+	Context       string            `json:"context"`
+	Target        string            `json:"target"`
+	Repo          string            `json:"repo"`
+	Tags          []string          `json:"tags"`
+	ExtraTags     []string          `json:"extra_tags"`
+	Platforms     []string          `json:"platforms"`
+	Args          map[string]string `json:"build_args"`
+	CacheFrom     []string          `json:"cache_from"`
+	CacheTo       []string          `json:"cache_to"`
+	Output        string            `json:"output"`
+}
+
+// ParseBuilds parses the JSON list of build definitions and merges each definition
+// with the given base build settings.
+func ParseBuilds(raw string, base docker.Build) ([]docker.Build, error) {
+	var defs []BuildDefinition
+
+	if err := json.Unmarshal([]byte(raw), &defs); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBuilds, err)
+	}
+
+	builds := make([]docker.Build, 0, len(defs))
+	names := make(map[string]bool, len(defs))
+
+	for i, def := range defs {
+		build := def.apply(base)
+
+		if build.Name == "" {
+			build.Name = fmt.Sprintf("build-%d", i+1)
+		}
+
+		if names[build.Name] {
+			return nil, fmt.Errorf("%w: duplicate build name %s", ErrInvalidBuilds, build.Name)
+		}
+
+		names[build.Name] = true
+		builds = append(builds, build)
+	}
+
+	return builds, nil
+}
+
+// helper function to create a copy of the base build with the definition values applied.
+func (d BuildDefinition) apply(base docker.Build) docker.Build {
+	b := base
+
+	b.Name = d.Name
+	b.Args = maps.Clone(base.Args)
+	b.Tags = slices.Clone(base.Tags)
+	b.ExtraTags = slices.Clone(base.ExtraTags)
+	b.Platforms = slices.Clone(base.Platforms)
+	b.CacheFrom = slices.Clone(base.CacheFrom)
+	b.CacheTo = slices.Clone(base.CacheTo)
+
+	if b.Args == nil {
+		b.Args = make(map[string]string)
+	}
+
+	maps.Copy(b.Args, d.Args)
+
+	overrideString(&b.Containerfile, d.Containerfile)
+	overrideString(&b.Context, d.Context)
+	overrideString(&b.Target, d.Target)
+	overrideString(&b.Repo, d.Repo)
+	overrideString(&b.Output, d.Output)
+
+	overrideSlice(&b.Tags, d.Tags)
+	overrideSlice(&b.ExtraTags, d.ExtraTags)
+	overrideSlice(&b.Platforms, d.Platforms)
+	overrideSlice(&b.CacheFrom, d.CacheFrom)
+	overrideSlice(&b.CacheTo, d.CacheTo)
+
+	return b
+}
+
+func overrideString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+func overrideSlice(dst *[]string, value []string) {
+	if len(value) > 0 {
+		*dst = value
+	}
+}
+
+// builds returns all builds to run, either the build matrix or the single build from the plugin settings.
+func (p *Plugin) builds() []*docker.Build {
+	if len(p.Settings.Builds) == 0 {
+		return []*docker.Build{&p.Settings.Build}
+	}
+
+	builds := make([]*docker.Build, 0, len(p.Settings.Builds))
+	for i := range p.Settings.Builds {
+		builds = append(builds, &p.Settings.Builds[i])
+	}
+
+	return builds
+}
+
+// runBuilds runs all builds with the configured parallelism and collects the build results.
+func (p *Plugin) runBuilds(ctx context.Context) ([]docker.Result, error) {
+	builds := p.builds()
+	results := make([]docker.Result, len(builds))
+	errs := make([]error, len(builds))
+
+	// check all builds before the first push to not push a part of a release
+	for _, build := range builds {
+		if err := p.protectImmutableTags(ctx, build, ""); err != nil {
+			if build.Name != "" {
+				err = fmt.Errorf("build %s: %w", build.Name, err)
+			}
+
+			return nil, err
+		}
+	}
+
+	parallel := max(p.Settings.BuildsParallel, 1)
+	sem := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+
+	for i, build := range builds {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i], errs[i] = p.runBuild(build)
+		}()
+	}
+
+	wg.Wait()
+
+	// a single build without matrix fails with the plain build error
+	if len(p.Settings.Builds) == 0 {
+		if errs[0] != nil {
+			return nil, errs[0]
+		}
+
+		return results, nil
+	}
+
+	failed := make([]error, 0)
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		log.Error().Msgf("build %s failed: %v", builds[i].Name, err)
+
+		failed = append(failed, fmt.Errorf("build %s: %w", builds[i].Name, err))
+	}
+
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("%d of %d %w:\n%w", len(failed), len(builds), ErrBuildsFailed, errors.Join(failed...))
+	}
+
+	return results, nil
+}
+
+// runBuild runs a single build and returns the build result.
+func (p *Plugin) runBuild(build *docker.Build) (docker.Result, error) {
+	result := docker.Result{
+		Name:      build.Name,
+		Tags:      build.Refs(),
+		Platforms: build.Platforms,
+	}
+
+	if result.Platforms == nil {
+		result.Platforms = []string{}
+	}
+
+	metadataFile, err := plugin_file.WriteTmpFile("build-metadata.json", "")
+	if err != nil {
+		return result, fmt.Errorf("error creating build metadata file: %w", err)
+	}
+
+	build.MetadataFile = metadataFile
+
+	defer os.Remove(build.MetadataFile)
+
+	if build.Name != "" {
+		log.Info().Msgf("start build %s", build.Name)
+	}
+
+	if err := p.runCmd(build.Run(p.Environment.Value())); err != nil {
+		if err := build.Cache.Discard(); err != nil {
+			log.Warn().Msgf("error discarding build cache: %v", err)
+		}
+
+		return result, err
+	}
+
+	if err := build.Cache.Commit(); err != nil {
+		log.Warn().Msgf("build cache not rotated: %v", err)
+	}
+
+	meta, err := docker.ReadMetadata(build.MetadataFile)
+	if err != nil {
+		return result, fmt.Errorf("error reading build metadata: %w", err)
+	}
+
+	result.Digest = meta.Digest
+
+	if p.Settings.Mode == ModePushByDigest {
+		if err := p.writeDigest(build, result.Digest); err != nil {
+			return result, err
+		}
+	}
+
+	if result.Digest != "" {
+		for _, ref := range result.Tags {
+			log.Info().Msgf("image digest %s: %s", ref, result.Digest)
+		}
+	}
+
+	return result, nil
+}
+
+// writeResults writes the build results to the result file. A single build result is written
+// as object, the results of a build matrix as list.
+func (p *Plugin) writeResults(results []docker.Result) error {
+	if p.Settings.ResultFile == "" {
+		return nil
+	}
+
+	var content any = results
+	if len(p.Settings.Builds) == 0 && len(results) == 1 {
+		content = results[0]
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding build result: %w", err)
+	}
+
+	if err := os.WriteFile(p.Settings.ResultFile, data, resultFilePerm); err != nil {
+		return fmt.Errorf("error writing build result: %w", err)
+	}
+
+	log.Info().Msgf("build result written to %s", p.Settings.ResultFile)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+func TestParseBuilds(t *testing.T) {
+	base := docker.Build{
+		Containerfile: "Containerfile",
+		Context:       ".",
+		Repo:          "example/app",
+		Tags:          []string{"latest"},
+		Args:          map[string]string{"VERSION": "1.0.0"},
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []docker.Build
+		wantErr bool
+	}{
+		{
+			name: "inherit base settings",
+			raw: `[
+				{"name": "api", "containerfile": "api/Containerfile", "repo": "example/api"},
+				{"context": "web", "build_args": {"NODE_ENV": "production"}, "tags": ["v1"]}
+			]`,
+			want: []docker.Build{
+				{
+					Name:          "api",
+					Containerfile: "api/Containerfile",
+					Context:       ".",
+					Repo:          "example/api",
+					Tags:          []string{"latest"},
+					Args:          map[string]string{"VERSION": "1.0.0"},
+				},
+				{
+					Name:          "build-2",
+					Containerfile: "Containerfile",
+					Context:       "web",
+					Repo:          "example/app",
+					Tags:          []string{"v1"},
+					Args:          map[string]string{"VERSION": "1.0.0", "NODE_ENV": "production"},
+				},
+			},
+		},
+		{
+			name:    "duplicate names",
+			raw:     `[{"name": "api"}, {"name": "api"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			raw:     `{"name": "api"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBuilds(tt.raw, base)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidBuilds)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, map[string]string{"VERSION": "1.0.0"}, base.Args)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+const (
+	gitBin = "/usr/bin/git"
+
+	eventPush        = "push"
+	eventPullRequest = "pull_request"
+)
+
+var ErrUnknownDiffBase = errors.New("unknown diff base")
+
+// ChangedFiles returns the files changed between the base and the head commit.
+func ChangedFiles(base, head string) ([]string, error) {
+	cmd := plugin_exec.Command(gitBin, "diff", "--name-only", base, head)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot detect changed files between %s and %s: %w", base, head, err)
+	}
+
+	files := make([]string, 0)
+
+	for _, file := range strings.Split(string(out), "\n") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// MergeBase returns the best common ancestor of the two commits.
+func MergeBase(a, b string) (string, error) {
+	cmd := plugin_exec.Command(gitBin, "merge-base", a, b)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot detect merge base of %s and %s: %w", a, b, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// MatchPaths returns true if at least one of the files matches a path filter. A filter matches
+// a file if it is the file itself, one of its parent directories or a glob pattern matching the
+// file or one of its parent directories.
+func MatchPaths(files, filters []string) bool {
+	for _, filter := range filters {
+		filter = path.Clean(strings.TrimPrefix(filter, "./"))
+		if filter == "." && len(files) > 0 {
+			return true
+		}
+
+		for _, file := range files {
+			for name := path.Clean(file); name != "." && name != "/"; name = path.Dir(name) {
+				if ok, _ := path.Match(filter, name); ok {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// filterUnchanged removes all builds without relevant changes since the previous commit or, for
+// pull requests, since the merge base with the target branch. If no build is left, ErrSkip is
+// returned. Only push and pull request events are filtered.
+func (p *Plugin) filterUnchanged() error {
+	if event := p.Metadata.Pipeline.Event; event != eventPush && event != eventPullRequest {
+		return nil
+	}
+
+	head := p.Metadata.Curr.SHA
+	if head == "" {
+		head = "HEAD"
+	}
+
+	base, err := p.diffBase(head)
+	if err != nil {
+		log.Warn().Msgf("build all images, %v", err)
+
+		return nil
+	}
+
+	files, err := ChangedFiles(base, head)
+	if err != nil {
+		log.Warn().Msgf("build all images, %v", err)
+
+		return nil
+	}
+
+	log.Debug().Msgf("changed files between %s and %s: %v", base, head, files)
+
+	if len(p.Settings.Builds) == 0 {
+		if !MatchPaths(files, p.pathFilters(&p.Settings.Build)) {
+			return fmt.Errorf("%w: no relevant changes between %s and %s", ErrSkip, base, head)
+		}
+
+		return nil
+	}
+
+	builds := make([]docker.Build, 0, len(p.Settings.Builds))
+
+	for _, build := range p.Settings.Builds {
+		if !MatchPaths(files, p.pathFilters(&build)) {
+			log.Info().Msgf("skip build %s: no relevant changes", build.Name)
+
+			continue
+		}
+
+		builds = append(builds, build)
+	}
+
+	if len(builds) == 0 {
+		return fmt.Errorf("%w: no relevant changes for any build between %s and %s", ErrSkip, base, head)
+	}
+
+	p.Settings.Builds = builds
+
+	return nil
+}
+
+// diffBase returns the commit to detect the changes of the head commit against. Pull requests
+// are compared to the merge base with the target branch, pushes to the previous commit.
+func (p *Plugin) diffBase(head string) (string, error) {
+	if p.Metadata.Pipeline.Event != eventPullRequest {
+		if p.Metadata.Prev.SHA != "" {
+			return p.Metadata.Prev.SHA, nil
+		}
+
+		return "HEAD~1", nil
+	}
+
+	target := p.Metadata.Curr.TargetBranch
+	if target == "" {
+		return "", fmt.Errorf("%w: target branch of the pull request is not set", ErrUnknownDiffBase)
+	}
+
+	// the target branch is usually only fetched as remote branch
+	var err error
+
+	for _, ref := range []string{"origin/" + target, target} {
+		var base string
+
+		if base, err = MergeBase(ref, head); err == nil {
+			return base, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %w", ErrUnknownDiffBase, err)
+}
+
+// pathFilters returns the configured path filters or the build context and Containerfile
+// of the build by default.
+func (p *Plugin) pathFilters(build *docker.Build) []string {
+	if len(p.Settings.SkipUnchangedPaths) > 0 {
+		return p.Settings.SkipUnchangedPaths
+	}
+
+	return []string{build.Context, build.Containerfile}
+}
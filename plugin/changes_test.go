@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestMatchPaths(t *testing.T) {
+	files := []string{"services/api/main.go", "README.md"}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    bool
+	}{
+		{name: "workspace context", filters: []string{"."}, want: true},
+		{name: "directory", filters: []string{"services/api"}, want: true},
+		{name: "directory with dot prefix", filters: []string{"./services/api/"}, want: true},
+		{name: "file", filters: []string{"README.md"}, want: true},
+		{name: "glob", filters: []string{"services/*"}, want: true},
+		{name: "other directory", filters: []string{"services/web", "web/Containerfile"}},
+		{name: "no filters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchPaths(files, tt.filters))
+		})
+	}
+}
+
+func TestFilterUnchanged(t *testing.T) {
+	if _, err := exec.LookPath(gitBin); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	git := func(args ...string) string {
+		out, err := exec.Command(gitBin, args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+
+		return strings.TrimSpace(string(out))
+	}
+
+	commit := func(file string) string {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		assert.NoError(t, os.WriteFile(file, []byte(file), 0o600))
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-qm", file)
+
+		return git("rev-parse", "HEAD")
+	}
+
+	git("init", "-q", "-b", "main")
+	base := commit("README.md")
+	git("checkout", "-q", "-b", "feature")
+	head := commit("api/main.go")
+	git("checkout", "-q", "main")
+	target := commit("web/Containerfile")
+
+	newPlugin := func(event string, builds ...docker.Build) *Plugin {
+		return &Plugin{
+			Plugin: &plugin_base.Plugin{
+				Metadata: plugin_base.Metadata{
+					Pipeline: plugin_base.Pipeline{Event: event},
+					Curr:     plugin_base.Commit{SHA: head},
+					Prev:     plugin_base.Commit{SHA: base},
+				},
+			},
+			Settings: &Settings{
+				Build:  docker.Build{Context: "web", Containerfile: "web/Containerfile"},
+				Builds: builds,
+			},
+		}
+	}
+
+	t.Run("skip unchanged build", func(t *testing.T) {
+		assert.ErrorIs(t, newPlugin(eventPush).filterUnchanged(), ErrSkip)
+	})
+
+	t.Run("ignore tag events", func(t *testing.T) {
+		assert.NoError(t, newPlugin("tag").filterUnchanged())
+	})
+
+	t.Run("custom path filters", func(t *testing.T) {
+		p := newPlugin(eventPullRequest)
+		p.Metadata.Curr.TargetBranch = "main"
+		p.Settings.SkipUnchangedPaths = []string{"api/*.go"}
+
+		assert.NoError(t, p.filterUnchanged())
+	})
+
+	t.Run("filter matrix builds", func(t *testing.T) {
+		p := newPlugin(eventPush,
+			docker.Build{Name: "api", Context: "api", Containerfile: "api/Containerfile"},
+			docker.Build{Name: "web", Context: "web", Containerfile: "web/Containerfile"},
+		)
+
+		assert.NoError(t, p.filterUnchanged())
+		assert.Len(t, p.Settings.Builds, 1)
+		assert.Equal(t, "api", p.Settings.Builds[0].Name)
+	})
+
+	t.Run("pull request ignores target branch changes", func(t *testing.T) {
+		p := newPlugin(eventPullRequest)
+		p.Metadata.Curr.TargetBranch = "main"
+		p.Metadata.Prev.SHA = target
+
+		assert.ErrorIs(t, p.filterUnchanged(), ErrSkip)
+	})
+
+	t.Run("pull request includes all changes since merge base", func(t *testing.T) {
+		p := newPlugin(eventPullRequest, docker.Build{Name: "api", Context: "api", Containerfile: "api/Containerfile"})
+		p.Metadata.Curr.TargetBranch = "main"
+		p.Metadata.Prev.SHA = head
+
+		assert.NoError(t, p.filterUnchanged())
+		assert.Len(t, p.Settings.Builds, 1)
+	})
+
+	t.Run("build all on unknown pull request target", func(t *testing.T) {
+		p := newPlugin(eventPullRequest)
+		p.Metadata.Curr.TargetBranch = "unknown"
+
+		assert.NoError(t, p.filterUnchanged())
+	})
+
+	t.Run("build all on unknown base", func(t *testing.T) {
+		p := newPlugin(eventPush)
+		p.Metadata.Prev.SHA = "0000000000000000000000000000000000000000"
+
+		assert.NoError(t, p.filterUnchanged())
+	})
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+var ErrMissingDigest = errors.New("missing image digest")
+
+// writeDigest records the digest of a build pushed by digest to the digests directory. Digests
+// of builds that were not pushed are not recorded, as they cannot be merged.
+func (p *Plugin) writeDigest(build *docker.Build, digest string) error {
+	if build.Dryrun {
+		log.Info().Msgf("image digest of %s not recorded, image was not pushed", build.Repo)
+
+		return nil
+	}
+
+	if digest == "" {
+		return fmt.Errorf("%w: build metadata contains no digest", ErrMissingDigest)
+	}
+
+	path, err := docker.WriteDigest(p.Settings.DigestsDir, docker.Digest{
+		Repo:      build.Repo,
+		Digest:    digest,
+		Platforms: build.Platforms,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing digest: %w", err)
+	}
+
+	log.Info().Msgf("image digest %s@%s recorded in %s", build.Repo, digest, path)
+
+	return nil
+}
+
+// merge creates a manifest list from all recorded digests with all target tags.
+func (p *Plugin) merge(ctx context.Context, homeDir string) error {
+	digests, err := docker.ReadDigests(p.Settings.DigestsDir)
+	if err != nil {
+		return fmt.Errorf("error reading digests: %w", err)
+	}
+
+	sources := make([]string, 0, len(digests))
+
+	for _, digest := range digests {
+		log.Info().Msgf("merge %s %v", digest.Source(), digest.Platforms)
+
+		sources = append(sources, digest.Source())
+	}
+
+	return p.createImage(ctx, homeDir, sources, p.mergedDigest)
+}
+
+// mergedDigest returns the digest of the manifest list created from the sources. The manifest
+// list is printed by a dry run of buildx imagetools without pushing it.
+func (p *Plugin) mergedDigest(_ context.Context, sources []string) (string, error) {
+	var manifest bytes.Buffer
+
+	imagetools := docker.Imagetools{
+		Sources: sources,
+		Refs:    p.Settings.Build.Refs(),
+		Dryrun:  true,
+	}
+
+	cmd := imagetools.Create()
+	cmd.Stdout = &manifest
+
+	if err := p.runCmd(cmd); err != nil {
+		return "", fmt.Errorf("error creating manifest list: %w", err)
+	}
+
+	return docker.ManifestDigest(manifest.Bytes()), nil
+}
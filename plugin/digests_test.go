@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+func TestWriteDigest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".digests")
+	p := &Plugin{Settings: &Settings{DigestsDir: dir}}
+	build := &docker.Build{Repo: "quay.io/octocat/app", Platforms: []string{"linux/arm64"}}
+
+	assert.ErrorIs(t, p.writeDigest(build, ""), ErrMissingDigest)
+	assert.NoError(t, p.writeDigest(build, "sha256:abc"))
+
+	digests, err := docker.ReadDigests(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []docker.Digest{
+		{Repo: "quay.io/octocat/app", Digest: "sha256:abc", Platforms: []string{"linux/arm64"}},
+	}, digests)
+}
+
+func TestWriteDigestDryrun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".digests")
+	p := &Plugin{Settings: &Settings{DigestsDir: dir}}
+	build := &docker.Build{Repo: "quay.io/octocat/app", Dryrun: true}
+
+	assert.NoError(t, p.writeDigest(build, "sha256:abc"))
+	assert.NoDirExists(t, dir)
+}
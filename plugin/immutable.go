@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+const (
+	ImmutablePolicyFail = "fail"
+	ImmutablePolicySkip = "skip"
+)
+
+var (
+	ErrImmutableTag           = errors.New("immutable tag already exists")
+	ErrInvalidImmutablePolicy = errors.New("invalid immutable tag policy")
+
+	semverTagPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?$`)
+)
+
+// Immutable defines the protection of existing semver tags in the registry.
+type Immutable struct {
+	Enabled bool
+	Policy  string
+}
+
+// Validate checks the immutable tag policy.
+func (i Immutable) Validate() error {
+	switch i.Policy {
+	case ImmutablePolicyFail, ImmutablePolicySkip:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidImmutablePolicy, i.Policy)
+	}
+}
+
+// IsImmutableTag returns true if the tag is a full semantic version, e.g. 1.2.3 or v1.2.3-rc.1.
+// Floating tags like 1 or 1.2 are not immutable.
+func IsImmutableTag(tag string) bool {
+	return semverTagPattern.MatchString(tag)
+}
+
+// protectImmutableTags checks the registry for existing immutable tags of the build before
+// anything is pushed. Existing tags that already point to the given digest are allowed, if the
+// digest is known before the push. With the fail policy any other existing tag fails the build,
+// with the skip policy these tags are removed from the build to not overwrite them.
+func (p *Plugin) protectImmutableTags(ctx context.Context, build *docker.Build, digest string) error {
+	if !p.Settings.Immutable.Enabled || build.Dryrun || build.Output != "" {
+		return nil
+	}
+
+	existing := make([]string, 0)
+	checked := make(map[string]bool)
+
+	exists := func(ref string) (bool, error) {
+		if found, ok := checked[ref]; ok {
+			return found, nil
+		}
+
+		repo, tag, ok := docker.SplitReference(ref)
+		if !ok || !IsImmutableTag(tag) {
+			return false, nil
+		}
+
+		username, password := p.registryCredentials(repo)
+
+		current, err := docker.NewRegistryClient(username, password).Digest(ctx, repo, tag)
+		if errors.Is(err, docker.ErrManifestNotFound) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("cannot check immutable tag %s: %w", ref, err)
+		}
+
+		if digest != "" && current == digest {
+			log.Info().Msgf("immutable tag %s already points to %s", ref, digest)
+
+			checked[ref] = false
+
+			return false, nil
+		}
+
+		existing = append(existing, fmt.Sprintf("%s (%s)", ref, current))
+		checked[ref] = true
+
+		return true, nil
+	}
+
+	tags := make([]string, 0, len(build.Tags))
+
+	for _, tag := range build.Tags {
+		found, err := exists(fmt.Sprintf("%s:%s", build.Repo, tag))
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+
+	extraTags := make([]string, 0, len(build.ExtraTags))
+
+	for _, ref := range build.ExtraTags {
+		found, err := exists(ref)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			extraTags = append(extraTags, ref)
+		}
+	}
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	if p.Settings.Immutable.Policy != ImmutablePolicySkip {
+		return fmt.Errorf("%w: %s", ErrImmutableTag, strings.Join(existing, ", "))
+	}
+
+	for _, ref := range existing {
+		log.Warn().Msgf("skip immutable tag %s: already exists", ref)
+	}
+
+	build.Tags = tags
+	build.ExtraTags = extraTags
+
+	return nil
+}
+
+// protectImmutableImage checks the target tags of an image created from the sources by digest,
+// e.g. in promote or merge mode. The digest of the created image is resolved before the check
+// to allow re-runs that push the same image.
+func (p *Plugin) protectImmutableImage(ctx context.Context, sources []string, resolve digestFunc) error {
+	build := &p.Settings.Build
+
+	if !p.Settings.Immutable.Enabled || build.Dryrun {
+		return nil
+	}
+
+	digest, err := resolve(ctx, sources)
+	if err != nil {
+		return err
+	}
+
+	return p.protectImmutableTags(ctx, build, digest)
+}
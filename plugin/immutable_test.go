@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestIsImmutableTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"1.2.3", true},
+		{"v1.2.3", true},
+		{"1.2.3-rc.1", true},
+		{"1.2.3-arm64", true},
+		{"1.2", false},
+		{"1", false},
+		{"latest", false},
+		{"01.2.3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsImmutableTag(tt.tag))
+		})
+	}
+}
+
+func TestImmutableValidate(t *testing.T) {
+	assert.NoError(t, Immutable{Enabled: true, Policy: ImmutablePolicyFail}.Validate())
+	assert.NoError(t, Immutable{Enabled: true, Policy: ImmutablePolicySkip}.Validate())
+	assert.ErrorIs(t, Immutable{Enabled: true, Policy: "overwrite"}.Validate(), ErrInvalidImmutablePolicy)
+}
+
+func newImmutableRegistry(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/v2/octocat/app/manifests/1.2.3", r.URL.Path == "/v2/octocat/app/manifests/sha-abc":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := http.DefaultClient
+	http.DefaultClient = server.Client()
+
+	t.Cleanup(func() { http.DefaultClient = client })
+
+	return strings.TrimPrefix(server.URL, "https://") + "/octocat/app"
+}
+
+func TestProtectImmutableTags(t *testing.T) {
+	repo := newImmutableRegistry(t)
+
+	newBuild := func() *docker.Build {
+		return &docker.Build{
+			Repo:      repo,
+			Tags:      []string{"latest", "1.2", "1.2.3", "1.2.4"},
+			ExtraTags: []string{repo + ":1.2.3"},
+		}
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{Immutable: Immutable{Enabled: true, Policy: ImmutablePolicyFail}}}
+		build := newBuild()
+
+		err := p.protectImmutableTags(context.Background(), build, "")
+		assert.ErrorIs(t, err, ErrImmutableTag)
+		assert.Contains(t, err.Error(), repo+":1.2.3")
+		assert.Equal(t, newBuild().Tags, build.Tags)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{Immutable: Immutable{Enabled: true, Policy: ImmutablePolicySkip}}}
+		build := newBuild()
+
+		assert.NoError(t, p.protectImmutableTags(context.Background(), build, ""))
+		assert.Equal(t, []string{"latest", "1.2", "1.2.4"}, build.Tags)
+		assert.Empty(t, build.ExtraTags)
+	})
+
+	t.Run("same digest", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{Immutable: Immutable{Enabled: true, Policy: ImmutablePolicyFail}}}
+		build := newBuild()
+
+		assert.NoError(t, p.protectImmutableTags(context.Background(), build, "sha256:abc"))
+		assert.Equal(t, newBuild().Tags, build.Tags)
+		assert.Equal(t, newBuild().ExtraTags, build.ExtraTags)
+	})
+}
+
+func TestProtectImmutableImage(t *testing.T) {
+	repo := newImmutableRegistry(t)
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{
+			name:   "same image",
+			source: repo + ":sha-abc",
+		},
+		{
+			name:   "same digest",
+			source: repo + "@sha256:abc",
+		},
+		{
+			name:    "different digest",
+			source:  repo + "@sha256:def",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Settings: &Settings{
+				Immutable: Immutable{Enabled: true, Policy: ImmutablePolicyFail},
+				Build:     docker.Build{Repo: repo, Tags: []string{"1.2.3"}},
+			}}
+
+			err := p.protectImmutableImage(context.Background(), []string{tt.source}, p.sourceDigest)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrImmutableTag)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"1.2.3"}, p.Settings.Build.Tags)
+		})
+	}
+}
+
+func TestImmutableBake(t *testing.T) {
+	p := &Plugin{
+		Plugin: &plugin_base.Plugin{},
+		Settings: &Settings{
+			Mode:      ModeBake,
+			Daemon:    docker.Daemon{Disabled: true},
+			Immutable: Immutable{Enabled: true, Policy: ImmutablePolicyFail},
+		},
+	}
+
+	assert.ErrorIs(t, p.Validate(), ErrInvalidMode)
+}
+
+func TestProtectImmutableTagsDisabled(t *testing.T) {
+	build := &docker.Build{
+		Repo:   "octocat/app",
+		Tags:   []string{"1.2.3"},
+		Dryrun: true,
+	}
+
+	for _, immutable := range []Immutable{{}, {Enabled: true, Policy: ImmutablePolicyFail}} {
+		p := &Plugin{Settings: &Settings{Immutable: immutable}}
+
+		assert.NoError(t, p.protectImmutableTags(context.Background(), build, ""))
+		assert.Equal(t, []string{"1.2.3"}, build.Tags)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
-	"github.com/cenkalti/backoff/v7"
 	"github.com/rs/zerolog/log"
 	"github.com/thegeeklab/wp-docker-buildx/docker"
 	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
@@ -17,17 +18,37 @@ import (
 	plugin_util "github.com/thegeeklab/wp-plugin-go/v6/util"
 )
 
-var ErrTypeAssertionFailed = errors.New("type assertion failed")
+var (
+	ErrTypeAssertionFailed = errors.New("type assertion failed")
+	ErrInvalidMode         = errors.New("invalid mode")
+	ErrSkip                = errors.New("skip step")
+)
 
 const (
-	strictFilePerm               = 0o600
-	daemonBackoffMaxRetries      = 3
-	daemonBackoffInitialInterval = 2 * time.Second
-	daemonBackoffMultiplier      = 3.5
+	ModeBuild   = "build"
+	ModeBake    = "bake"
+	ModeCleanup = "cleanup"
+	ModePromote = "promote"
+
+	ModePushByDigest = "push-by-digest"
+	ModeMerge        = "merge"
+)
+
+const (
+	strictFilePerm   = 0o600
+	dockerConfigEnv  = "DOCKER_CONFIG"
+	dockerConfigFile = "config.json"
+	resultFilePerm   = 0o644
 )
 
 func (p *Plugin) run(ctx context.Context) error {
 	if err := p.Validate(); err != nil {
+		if errors.Is(err, ErrSkip) {
+			log.Info().Msg(err.Error())
+
+			return nil
+		}
+
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
@@ -46,14 +67,64 @@ func (p *Plugin) Validate() error {
 	p.Settings.Build.Branch = p.Metadata.Repository.Branch
 	p.Settings.Build.Ref = p.Metadata.Curr.Ref
 	p.Settings.Daemon.Registry = p.Settings.Registry.Address
+	p.Settings.Build.CacheTo = docker.JoinCacheEntries(p.Settings.Build.CacheTo)
+
+	if p.Settings.Immutable.Enabled {
+		if err := p.Settings.Immutable.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if !p.Settings.Daemon.Disabled {
+		if _, err := p.Settings.Daemon.GenerateConfig(); err != nil {
+			return err
+		}
+	}
+
+	switch p.Settings.Mode {
+	case ModeBuild, ModeBake:
+	case ModeCleanup:
+		if p.Metadata.Curr.PullRequest == 0 {
+			return fmt.Errorf("%w: no pull request to clean up preview images for", ErrSkip)
+		}
+	case ModePromote:
+		if p.Settings.PromoteSource == "" {
+			return fmt.Errorf("%w: promote mode requires a source image", ErrInvalidPromote)
+		}
+	case ModePushByDigest, ModeMerge:
+		if p.Settings.BuildsRaw != "" {
+			return fmt.Errorf("%w: %s mode does not support builds", ErrInvalidMode, p.Settings.Mode)
+		}
+
+		if p.Settings.Build.Repo == "" {
+			return fmt.Errorf("%w: %s mode requires a repo", ErrInvalidMode, p.Settings.Mode)
+		}
+
+		if p.Settings.Mode == ModePushByDigest && p.Settings.Build.Output != "" {
+			return fmt.Errorf("%w: %s mode does not support a custom output", ErrInvalidMode, p.Settings.Mode)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidMode, p.Settings.Mode)
+	}
+
+	if p.Settings.TagPrefix != "" {
+		ref, ok := StripTagPrefix(p.Settings.Build.Ref, p.Settings.TagPrefix)
+		if !ok {
+			return fmt.Errorf("%w: %s does not match tag prefix %s", ErrSkip, p.Settings.Build.Ref, p.Settings.TagPrefix)
+		}
+
+		p.Settings.Build.Ref = ref
+	}
 
 	if p.Settings.Build.TagsAuto {
+		tags := make([]string, 0)
+
 		// return true if tag event or default branch
 		if plugin_tag.IsTaggable(
 			p.Settings.Build.Ref,
 			p.Settings.Build.Branch,
 		) {
-			p.Settings.Build.Tags, err = plugin_tag.SemverTagSuffix(
+			tags, err = plugin_tag.SemverTagSuffix(
 				p.Settings.Build.Ref,
 				p.Settings.Build.TagsSuffix,
 				true,
@@ -61,87 +132,186 @@ func (p *Plugin) Validate() error {
 			if err != nil {
 				return fmt.Errorf("cannot generate tags from %s, invalid semantic version: %w", p.Settings.Build.Ref, err)
 			}
-		} else {
+		} else if !p.Settings.TagStrategies.Enabled() {
 			log.Info().Msgf("skip auto-tagging for %s, not on default branch or tag", p.Settings.Build.Ref)
+		}
 
-			return nil
+		strategyTags, strategyErr := p.StrategyTags()
+		if strategyErr != nil {
+			return strategyErr
+		}
+
+		if tags = append(tags, strategyTags...); len(tags) > 0 {
+			p.Settings.Build.Tags = tags
 		}
 	}
 
-	if p.Settings.Build.LabelsAuto {
-		p.Settings.Build.Labels = p.GenerateLabels()
+	if err := p.validatePreview(); err != nil {
+		return err
 	}
 
-	return nil
-}
+	if err := p.ReadSecretFiles(); err != nil {
+		return err
+	}
 
-// Execute provides the implementation of the plugin.
-func (p *Plugin) Execute(ctx context.Context) error {
-	var err error
+	if err := ValidateCredHelpers(p.Settings.CredHelpers); err != nil {
+		return err
+	}
 
-	homeDir := plugin_util.GetUserHomeDir()
-	batchCmd := make([]*plugin_exec.Cmd, 0)
+	if p.Settings.RegistriesRaw != "" {
+		if p.Settings.Registries, err = ParseRegistries(p.Settings.RegistriesRaw); err != nil {
+			return err
+		}
+	}
 
-	// start the Docker daemon server
-	//nolint: nestif
-	if !p.Settings.Daemon.Disabled {
-		// If no custom DNS value set start internal DNS server
-		if len(p.Settings.Daemon.DNS) == 0 {
-			ip, err := GetContainerIP()
-			if err != nil {
-				log.Warn().Msgf("error detecting IP address: %v", err)
-			}
+	if p.Settings.BuildsRaw != "" {
+		if p.Settings.Builds, err = ParseBuilds(p.Settings.BuildsRaw, p.Settings.Build); err != nil {
+			return err
+		}
+	}
 
-			if ip != "" {
-				log.Debug().Msgf("discovered IP address: %v", ip)
+	if p.Settings.SkipUnchanged {
+		if err := p.filterUnchanged(); err != nil {
+			return err
+		}
+	}
 
-				cmd := p.Settings.Daemon.StartCoreDNS()
+	refErrs := make([]error, 0)
 
-				go func() {
-					_ = cmd.Run()
-				}()
+	for _, build := range p.builds() {
+		p.applyPreview(build)
 
-				p.Settings.Daemon.DNS = append(p.Settings.Daemon.DNS, ip)
+		if err := p.RenderTags(build); err != nil {
+			return err
+		}
+
+		build.ExtraTags = append(build.ExtraTags, RegistryTags(p.Settings.Registries, build)...)
+
+		if p.Settings.SanitizeRefs {
+			SanitizeRefs(build)
+		}
+
+		// labels are generated from the final tags of the build
+		if build.LabelsAuto {
+			build.Labels = p.GenerateLabels(build)
+		} else {
+			build.Labels = slices.Concat(build.Labels, p.PreviewLabels())
+		}
+
+		if err := build.ValidateRefs(); err != nil {
+			if build.Name != "" {
+				err = fmt.Errorf("build %s: %w", build.Name, err)
 			}
+
+			refErrs = append(refErrs, err)
 		}
 
-		cmd := p.Settings.Daemon.Start()
+		if err := build.ApplyCache(p.Metadata.Curr.Branch, p.Metadata.Repository.Branch); err != nil {
+			return err
+		}
 
-		go func() {
-			_ = cmd.Run()
-		}()
+		for _, entry := range build.CacheTo {
+			if err := docker.ValidateCacheEntry(entry); err != nil {
+				return err
+			}
+		}
 	}
 
-	// poll the docker daemon until it is started. This ensures the daemon is
-	// ready to accept connections before we proceed.
-	for i := 0; i < 15; i++ {
-		cmd := docker.Info()
+	if p.Settings.Mode == ModePromote {
+		if err := p.validatePromote(); err != nil {
+			refErrs = append(refErrs, err)
+		}
+	}
 
-		err := cmd.Run()
-		if err == nil {
-			break
+	if p.Settings.Mode == ModeBake {
+		if p.Settings.Immutable.Enabled {
+			return fmt.Errorf("%w: %s mode does not support immutable tags", ErrInvalidMode, p.Settings.Mode)
 		}
 
-		time.Sleep(time.Second * 1)
+		if err := p.Settings.Bake.Validate(&p.Settings.Build); err != nil {
+			return err
+		}
 	}
 
-	if p.Settings.Registry.Config != "" {
-		path := filepath.Join(homeDir, ".docker", "config.json")
-		if err := os.MkdirAll(filepath.Dir(path), strictFilePerm); err != nil {
-			return err
+	if p.Settings.Mode == ModeMerge && len(p.Settings.Build.Refs()) == 0 {
+		return fmt.Errorf("%w: merge mode requires tags", ErrInvalidMode)
+	}
+
+	if len(refErrs) > 0 {
+		return fmt.Errorf("%w:\n%w", docker.ErrInvalidReference, errors.Join(refErrs...))
+	}
+
+	p.applyPushPolicy()
+
+	if p.Settings.Mode == ModePushByDigest {
+		p.Settings.Build.Output = p.Settings.Build.DigestOutput()
+		p.Settings.Build.Tags = nil
+		p.Settings.Build.ExtraTags = nil
+	}
+
+	return nil
+}
+
+// Execute provides the implementation of the plugin.
+func (p *Plugin) Execute(ctx context.Context) (err error) {
+	homeDir := plugin_util.GetUserHomeDir()
+	batchCmd := make([]*plugin_exec.Cmd, 0)
+
+	p.masker = NewMasker(p.secrets()...)
+
+	switch p.Settings.Mode {
+	case ModeCleanup:
+		return p.cleanupPreview(ctx)
+	case ModePromote:
+		return p.promote(ctx, homeDir)
+	case ModeMerge:
+		return p.merge(ctx, homeDir)
+	}
+
+	// start the Docker daemon server
+	var manager *docker.DaemonManager
+
+	defer func() {
+		if err != nil {
+			p.dumpDaemonLogs(manager)
 		}
+	}()
+
+	if !p.Settings.Daemon.Disabled {
+		var stop func()
+
+		manager, stop, err = p.startDaemon(ctx)
+		defer stop()
 
-		if err := WriteDockerConf(path, p.Settings.Registry.Config); err != nil {
-			return fmt.Errorf("error writing docker config: %w", err)
+		if err != nil {
+			return fmt.Errorf("error starting docker daemon: %w", err)
+		}
+	} else {
+		// wait for an external docker daemon, e.g. a service that is still starting
+		if err := docker.WaitDaemon(ctx, p.Settings.Daemon.StartTimeout, docker.Version); err != nil {
+			return fmt.Errorf("error connecting to docker daemon: %w", err)
 		}
 	}
 
+	configDir, err := p.setupDockerConfig(homeDir)
+	if err != nil {
+		return fmt.Errorf("error writing docker config: %w", err)
+	}
+
+	defer p.cleanupDockerConfig(configDir)
+
 	if p.Settings.Registry.Password != "" {
-		if err := p.Settings.Registry.Login().Run(); err != nil {
+		if err := p.runCmd(p.Settings.Registry.Login()); err != nil {
 			return fmt.Errorf("error authenticating: %w", err)
 		}
 	}
 
+	for _, registry := range p.Settings.Registries {
+		if err := p.runCmd(registry.Login()); err != nil {
+			return fmt.Errorf("error authenticating with %s: %w", registry.Address, err)
+		}
+	}
+
 	buildkitConf := p.Settings.BuildkitConfig
 	if buildkitConf != "" {
 		if p.Settings.Daemon.BuildkitConfigFile, err = plugin_file.WriteTmpFile("buildkit.toml", buildkitConf); err != nil {
@@ -152,50 +322,222 @@ func (p *Plugin) Execute(ctx context.Context) error {
 	}
 
 	switch {
-	case p.Settings.Registry.Password != "":
+	case p.Settings.Registry.Password != "" || len(p.Settings.Registries) > 0:
 		log.Info().Msgf("Detected registry credentials")
 	case p.Settings.Registry.Config != "":
 		log.Info().Msgf("Detected registry credentials file")
+	case len(p.Settings.CredHelpers) > 0:
+		log.Info().Msgf("Detected registry credential helpers")
 	default:
 		log.Info().Msgf("Registry credentials or Docker config not provided. Guest mode enabled.")
 	}
 
-	p.Settings.Build.AddProxyBuildArgs()
+	for _, build := range p.builds() {
+		build.AddProxyBuildArgs()
+	}
+
+	batchCmd = append(batchCmd, docker.Version())
+	batchCmd = append(batchCmd, docker.Info())
+	batchCmd = append(batchCmd, p.Settings.Daemon.CreateBuilder())
+	batchCmd = append(batchCmd, p.Settings.Daemon.ListBuilder())
+
+	switch p.Settings.Mode {
+	case ModeBake:
+		if p.Settings.Bake.MetadataFile, err = plugin_file.WriteTmpFile("bake-metadata.json", ""); err != nil {
+			return fmt.Errorf("error creating bake metadata file: %w", err)
+		}
 
-	bf := backoff.NewExponentialBackOff()
-	bf.InitialInterval = daemonBackoffInitialInterval
-	bf.Multiplier = daemonBackoffMultiplier
+		defer os.Remove(p.Settings.Bake.MetadataFile)
 
-	bfo := func() (any, error) {
-		return nil, docker.Version().Run()
+		batchCmd = append(batchCmd, p.Settings.Bake.Run(&p.Settings.Build, p.Environment.Value()))
 	}
 
-	bfn := func(err error, delay time.Duration) {
-		log.Error().Msgf("failed to run docker version command: %v: retry in %s", err, delay.Truncate(time.Second))
+	for _, cmd := range batchCmd {
+		if cmd == nil {
+			continue
+		}
+
+		if err := p.runCmd(cmd); err != nil {
+			if err := p.Settings.Build.Cache.Discard(); err != nil {
+				log.Warn().Msgf("build cache not discarded: %v", err)
+			}
+
+			return err
+		}
 	}
 
-	_, err = backoff.Retry(ctx, bfo,
-		backoff.WithBackOff(bf),
-		backoff.WithMaxTries(daemonBackoffMaxRetries),
-		backoff.WithNotify(bfn))
+	if p.Settings.Mode == ModeBake {
+		if err := p.Settings.Build.Cache.Commit(); err != nil {
+			log.Warn().Msgf("build cache not rotated: %v", err)
+		}
+
+		return p.reportBake()
+	}
+
+	results, err := p.runBuilds(ctx)
 	if err != nil {
 		return err
 	}
 
-	batchCmd = append(batchCmd, docker.Info())
-	batchCmd = append(batchCmd, p.Settings.Daemon.CreateBuilder())
-	batchCmd = append(batchCmd, p.Settings.Daemon.ListBuilder())
-	batchCmd = append(batchCmd, p.Settings.Build.Run(p.Environment.Value()))
+	return p.writeResults(results)
+}
 
-	for _, cmd := range batchCmd {
-		if cmd == nil {
+// startDaemon starts CoreDNS and the docker daemon and waits until the daemon is ready. The
+// returned function stops all processes, flushes their masked output and removes the daemon config file.
+func (p *Plugin) startDaemon(ctx context.Context) (*docker.DaemonManager, func(), error) {
+	manager := docker.NewDaemonManager(ctx, p.Settings.Daemon.StartTimeout)
+	cleanups := make([]func(), 0)
+
+	stop := func() {
+		manager.Stop()
+
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}
+
+	// If no custom DNS value set start internal DNS server
+	if len(p.Settings.Daemon.DNS) == 0 {
+		ip, err := GetContainerIP()
+		if err != nil {
+			log.Warn().Msgf("error detecting IP address: %v", err)
+		}
+
+		if ip != "" {
+			log.Debug().Msgf("discovered IP address: %v", ip)
+
+			cmd := p.Settings.Daemon.StartCoreDNS()
+			cleanups = append(cleanups, p.prepareCmd(cmd))
+
+			if err := manager.StartCoreDNS(cmd); err != nil {
+				log.Warn().Msgf("error starting CoreDNS: %v", err)
+			} else {
+				p.Settings.Daemon.DNS = append(p.Settings.Daemon.DNS, ip)
+			}
+		}
+	}
+
+	config, err := p.Settings.Daemon.GenerateConfig()
+	if err != nil {
+		return manager, stop, err
+	}
+
+	if p.Settings.Daemon.ConfigFile, err = plugin_file.WriteTmpFile("daemon.json", string(config)); err != nil {
+		return manager, stop, fmt.Errorf("error writing docker daemon config: %w", err)
+	}
+
+	cleanups = append(cleanups, func() { _ = os.Remove(p.Settings.Daemon.ConfigFile) })
+
+	log.Debug().Msgf("docker daemon config:\n%s", config)
+
+	cmd := p.Settings.Daemon.Start()
+	cleanups = append(cleanups, p.prepareCmd(cmd))
+
+	// wait for the docker daemon to be ready to accept connections before we proceed
+	return manager, stop, manager.StartDaemon(ctx, cmd)
+}
+
+// dumpDaemonLogs writes the last lines of the docker daemon and CoreDNS output to stderr.
+// In debug mode the output is already streamed and not dumped again.
+func (p *Plugin) dumpDaemonLogs(manager *docker.DaemonManager) {
+	if manager == nil || p.Settings.Daemon.Debug {
+		return
+	}
+
+	masker := p.masker
+	if masker == nil {
+		masker = NewMasker()
+	}
+
+	w := masker.Writer(os.Stderr)
+	manager.DumpLogs(w)
+
+	_ = w.Flush()
+}
+
+// setupDockerConfig creates an isolated docker config directory for this run and points
+// DOCKER_CONFIG to it. An existing docker config of the runner is used as base config.
+func (p *Plugin) setupDockerConfig(homeDir string) (string, error) {
+	dir, err := os.MkdirTemp("", "docker-config-")
+	if err != nil {
+		return "", err
+	}
+
+	src := filepath.Join(homeDir, ".docker", dockerConfigFile)
+	if env := os.Getenv(dockerConfigEnv); env != "" {
+		src = filepath.Join(env, dockerConfigFile)
+	}
+
+	path := filepath.Join(dir, dockerConfigFile)
+
+	if content, err := os.ReadFile(src); err == nil {
+		err = os.WriteFile(path, content, strictFilePerm)
+		if err != nil {
+			_ = os.RemoveAll(dir)
+
+			return "", err
+		}
+	}
+
+	if err := WriteDockerConf(path, p.Settings.Registry.Config, p.Settings.CredHelpers); err != nil {
+		_ = os.RemoveAll(dir)
+
+		return "", err
+	}
+
+	p.dockerConfigEnv, p.dockerConfigEnvSet = os.LookupEnv(dockerConfigEnv)
+
+	log.Debug().Msgf("using isolated docker config %s", dir)
+
+	return dir, os.Setenv(dockerConfigEnv, dir)
+}
+
+// cleanupDockerConfig logs out of all registries and removes the isolated docker config directory.
+func (p *Plugin) cleanupDockerConfig(dir string) {
+	if dir == "" {
+		return
+	}
+
+	registries := append([]docker.Registry{p.Settings.Registry}, p.Settings.Registries...)
+
+	for _, registry := range registries {
+		if registry.Password == "" {
 			continue
 		}
 
-		if err := cmd.Run(); err != nil {
-			return err
+		if err := p.runCmd(registry.Logout()); err != nil {
+			log.Warn().Msgf("error logging out of %s: %v", registry.Address, err)
 		}
 	}
 
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warn().Msgf("error removing docker config %s: %v", dir, err)
+	}
+
+	if p.dockerConfigEnvSet {
+		_ = os.Setenv(dockerConfigEnv, p.dockerConfigEnv)
+	} else {
+		_ = os.Unsetenv(dockerConfigEnv)
+	}
+}
+
+// reportBake logs the results of all targets built in bake mode.
+func (p *Plugin) reportBake() error {
+	results, err := docker.ReadBakeMetadata(p.Settings.Bake.MetadataFile)
+	if err != nil {
+		return fmt.Errorf("error reading bake metadata: %w", err)
+	}
+
+	for _, target := range slices.Sorted(maps.Keys(results)) {
+		result := results[target]
+		if result.Digest == "" {
+			log.Info().Msgf("bake target %s: built without image digest", target)
+
+			continue
+		}
+
+		log.Info().Msgf("bake target %s: %s (%s)", target, result.Digest, result.ImageName)
+	}
+
 	return nil
 }
@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	plugin_exec "github.com/thegeeklab/wp-plugin-go/v6/exec"
+)
+
+const (
+	maskString = "********"
+	// secrets shorter than this are not masked to keep the output readable
+	minSecretLength = 4
+)
+
+//nolint:gochecknoglobals
+var sensitiveArgKeys = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "PRIVATE_KEY", "API_KEY", "ACCESS_KEY"}
+
+// Masker replaces secret values in the command output.
+type Masker struct {
+	replacer *strings.Replacer
+}
+
+// NewMasker creates a masker for the given secrets. Multi-line secrets are masked line by line.
+func NewMasker(secrets ...string) *Masker {
+	values := make([]string, 0)
+
+	for _, secret := range secrets {
+		for _, line := range strings.Split(secret, "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) < minSecretLength || slices.Contains(values, line) {
+				continue
+			}
+
+			values = append(values, line)
+		}
+	}
+
+	// replace longer secrets first if secrets overlap
+	slices.SortFunc(values, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	pairs := make([]string, 0, len(values)*2) //nolint:mnd
+	for _, value := range values {
+		pairs = append(pairs, value, maskString)
+	}
+
+	return &Masker{replacer: strings.NewReplacer(pairs...)}
+}
+
+// Mask replaces all secrets in the given string.
+func (m *Masker) Mask(s string) string {
+	return m.replacer.Replace(s)
+}
+
+// Writer returns a line buffered writer that masks all secrets before writing to w.
+func (m *Masker) Writer(w io.Writer) *MaskWriter {
+	return &MaskWriter{masker: m, w: w}
+}
+
+// MaskWriter masks secrets line by line, so secrets split across multiple writes are masked as well.
+type MaskWriter struct {
+	masker *Masker
+	w      io.Writer
+	buf    bytes.Buffer
+	mu     sync.Mutex
+}
+
+// Write implements io.Writer.
+func (mw *MaskWriter) Write(p []byte) (int, error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	mw.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(mw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+
+		line := mw.buf.Next(i + 1)
+		if _, err := io.WriteString(mw.w, mw.masker.Mask(string(line))); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the remaining buffered output.
+func (mw *MaskWriter) Flush() error {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	if mw.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(mw.w, mw.masker.Mask(mw.buf.String()))
+	mw.buf.Reset()
+
+	return err
+}
+
+// helper function to collect all secret values that must not appear in the output.
+func (p *Plugin) secrets() []string {
+	secrets := []string{p.Settings.Registry.Password}
+
+	for _, registry := range p.Settings.Registries {
+		secrets = append(secrets, registry.Password)
+	}
+
+	for _, secret := range p.Settings.Build.Secrets {
+		if value := secretEnvValue(secret); value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+
+	for _, build := range p.builds() {
+		for key, value := range build.Args {
+			if isSensitiveArg(key, p.Settings.ArgsMasked) {
+				secrets = append(secrets, value)
+			}
+		}
+
+		for _, key := range build.ArgsEnv {
+			if isSensitiveArg(key, p.Settings.ArgsMasked) {
+				secrets = append(secrets, os.Getenv(key))
+			}
+		}
+	}
+
+	return secrets
+}
+
+// helper function to get the value of a build secret that is read from the environment.
+func secretEnvValue(secret string) string {
+	var id, env string
+
+	for _, field := range strings.Split(secret, ",") {
+		key, value, _ := strings.Cut(field, "=")
+
+		switch key {
+		case "id":
+			id = value
+		case "env":
+			env = value
+		case "src", "source":
+			return ""
+		}
+	}
+
+	if env == "" {
+		env = id
+	}
+
+	if env == "" {
+		return ""
+	}
+
+	return os.Getenv(env)
+}
+
+// helper function to check if a build arg contains a sensitive value.
+func isSensitiveArg(key string, masked []string) bool {
+	if slices.Contains(masked, key) {
+		return true
+	}
+
+	upper := strings.ToUpper(key)
+	for _, sensitive := range sensitiveArgKeys {
+		if strings.Contains(upper, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runCmd runs the command with all secrets masked in the command trace and output.
+func (p *Plugin) runCmd(cmd *plugin_exec.Cmd) error {
+	flush := p.prepareCmd(cmd)
+	defer flush()
+
+	return cmd.Run()
+}
+
+// prepareCmd masks all secrets in the command output and prints the masked command trace.
+// The returned function flushes the remaining buffered output after the command has exited.
+func (p *Plugin) prepareCmd(cmd *plugin_exec.Cmd) func() {
+	masker := p.masker
+	if masker == nil {
+		masker = NewMasker()
+	}
+
+	writers := make([]*MaskWriter, 0)
+
+	if cmd.Stdout != nil {
+		stdout := masker.Writer(cmd.Stdout)
+		cmd.Stdout = stdout
+		writers = append(writers, stdout)
+	}
+
+	if cmd.Stderr != nil {
+		stderr := masker.Writer(cmd.Stderr)
+		cmd.Stderr = stderr
+		writers = append(writers, stderr)
+	}
+
+	if cmd.Trace {
+		cmd.Trace = false
+
+		_, _ = io.WriteString(os.Stdout, masker.Mask("+ "+strings.Join(cmd.Args, " ")+"\n"))
+	}
+
+	return func() {
+		for _, w := range writers {
+			_ = w.Flush()
+		}
+	}
+}
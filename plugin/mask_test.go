@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskerMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		input   string
+		want    string
+	}{
+		{
+			name:    "single secret",
+			secrets: []string{"s3cr3t-pass"},
+			input:   "docker login -p s3cr3t-pass",
+			want:    "docker login -p ********",
+		},
+		{
+			name:    "overlapping secrets",
+			secrets: []string{"token", "token-extended"},
+			input:   "value=token-extended",
+			want:    "value=********",
+		},
+		{
+			name:    "multi-line secret",
+			secrets: []string{"line-one\nline-two\n"},
+			input:   "line-one and line-two",
+			want:    "******** and ********",
+		},
+		{
+			name:    "short and empty secrets",
+			secrets: []string{"", "abc"},
+			input:   "abc",
+			want:    "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewMasker(tt.secrets...).Mask(tt.input))
+		})
+	}
+}
+
+func TestMaskWriter(t *testing.T) {
+	var out bytes.Buffer
+
+	w := NewMasker("s3cr3t-pass").Writer(&out)
+
+	_, err := w.Write([]byte("password: s3cr"))
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	_, err = w.Write([]byte("3t-pass\nremaining s3cr3t-pass"))
+	assert.NoError(t, err)
+	assert.Equal(t, "password: ********\n", out.String())
+
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "password: ********\nremaining ********", out.String())
+}
+
+func TestSecretEnvValue(t *testing.T) {
+	t.Setenv("SECRET_TOKEN", "token-value")
+	t.Setenv("OTHER_TOKEN", "other-value")
+
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+	}{
+		{
+			name:   "env from id",
+			secret: "id=SECRET_TOKEN",
+			want:   "token-value",
+		},
+		{
+			name:   "explicit env",
+			secret: "id=token,env=OTHER_TOKEN",
+			want:   "other-value",
+		},
+		{
+			name:   "file secret",
+			secret: "id=SECRET_TOKEN,src=file.txt",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, secretEnvValue(tt.secret))
+		})
+	}
+}
+
+func TestIsSensitiveArg(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		masked []string
+		want   bool
+	}{
+		{
+			name: "password key",
+			key:  "db_password",
+			want: true,
+		},
+		{
+			name: "api key",
+			key:  "NPM_API_KEY",
+			want: true,
+		},
+		{
+			name:   "explicitly masked",
+			key:    "LICENSE",
+			masked: []string{"LICENSE"},
+			want:   true,
+		},
+		{
+			name: "regular key",
+			key:  "VERSION",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSensitiveArg(tt.key, tt.masked))
+		})
+	}
+}
@@ -17,15 +17,42 @@ type Plugin struct {
 	Repository *plugin_base.Repository
 	Commit     *plugin_base.Commit
 	Settings   *Settings
+
+	masker             *Masker
+	dockerConfigEnv    string
+	dockerConfigEnvSet bool
 }
 
 // Settings for the Plugin.
 type Settings struct {
-	BuildkitConfig string
+	BuildkitConfig     string
+	BuildkitConfigFile string
+	PasswordFile       string
+	RegistryConfigFile string
+	ArgsMasked         []string
+	CredHelpers        map[string]string
+	Mode               string
+	ResultFile         string
+	BuildsRaw          string
+	BuildsParallel     int
+	RegistriesRaw      string
+	TagStrategies      TagStrategies
+	SanitizeRefs       bool
+	TagPrefix          string
+	SkipUnchanged      bool
+	SkipUnchangedPaths []string
+	PushPolicy         PushPolicy
+	Preview            Preview
+	Immutable          Immutable
+	PromoteSource      string
+	DigestsDir         string
 
-	Daemon   docker.Daemon
-	Registry docker.Registry
-	Build    docker.Build
+	Daemon     docker.Daemon
+	Registry   docker.Registry
+	Registries []docker.Registry
+	Build      docker.Build
+	Bake       docker.Bake
+	Builds     []docker.Build
 }
 
 func New(e plugin_base.ExecuteFunc, build ...string) *Plugin {
@@ -70,11 +97,48 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Build.Dryrun,
 			Category:    category,
 		},
+		&cli.BoolFlag{
+			Name:        "preview",
+			Sources:     cli.EnvVars("PLUGIN_PREVIEW"),
+			Usage:       "build preview images tagged as pr-<number> for pull requests",
+			Value:       false,
+			Destination: &settings.Preview.Enabled,
+			Category:    category,
+		},
 		&cli.StringFlag{
+			Name:        "preview.expires",
+			Sources:     cli.EnvVars("PLUGIN_PREVIEW_EXPIRES"),
+			Usage:       "expiry of preview images set as registry-specific label",
+			Value:       "1w",
+			Destination: &settings.Preview.Expires,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "push.events",
+			Sources:     cli.EnvVars("PLUGIN_PUSH_EVENTS"),
+			Usage:       "pipeline events to push images for",
+			Destination: &settings.PushPolicy.Events,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "push.branches",
+			Sources:     cli.EnvVars("PLUGIN_PUSH_BRANCHES"),
+			Usage:       "branch patterns to push images for",
+			Destination: &settings.PushPolicy.Branches,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "push.refs",
+			Sources:     cli.EnvVars("PLUGIN_PUSH_REFS"),
+			Usage:       "git ref patterns to push images for",
+			Destination: &settings.PushPolicy.Refs,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
 			Name:        "daemon.mirror",
 			Sources:     cli.EnvVars("PLUGIN_MIRROR", "DOCKER_PLUGIN_MIRROR"),
-			Usage:       "registry mirror to pull images",
-			Destination: &settings.Daemon.Mirror,
+			Usage:       "registry mirrors to pull images",
+			Destination: &settings.Daemon.Mirrors,
 			DefaultText: "$DOCKER_PLUGIN_MIRROR",
 			Category:    category,
 		},
@@ -161,6 +225,14 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Daemon.Disabled,
 			Category:    category,
 		},
+		&cli.DurationFlag{
+			Name:        "daemon.start-timeout",
+			Sources:     cli.EnvVars("PLUGIN_DAEMON_START_TIMEOUT"),
+			Usage:       "max time to wait for the docker daemon to be ready",
+			Value:       docker.DefaultDaemonStartTimeout,
+			Destination: &settings.Daemon.StartTimeout,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "daemon.buildkit-config",
 			Sources:     cli.EnvVars("PLUGIN_BUILDKIT_CONFIG"),
@@ -168,6 +240,48 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.BuildkitConfig,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "daemon.buildkit-config-file",
+			Sources:     cli.EnvVars("PLUGIN_BUILDKIT_CONFIG_FILE"),
+			Usage:       "path to a file containing the docker buildkit toml config",
+			Destination: &settings.BuildkitConfigFile,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "daemon.log-level",
+			Sources:     cli.EnvVars("PLUGIN_DAEMON_LOG_LEVEL"),
+			Usage:       "docker daemon log level (debug, info, warn, error, fatal)",
+			Destination: &settings.Daemon.LogLevel,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "daemon.default-address-pools",
+			Sources:     cli.EnvVars("PLUGIN_DEFAULT_ADDRESS_POOLS"),
+			Usage:       "docker daemon default address pools in the format base:size",
+			Destination: &settings.Daemon.DefaultAddressPools,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "daemon.features",
+			Sources:     cli.EnvVars("PLUGIN_DAEMON_FEATURES"),
+			Usage:       "docker daemon features in the format name=true|false",
+			Destination: &settings.Daemon.Features,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "daemon.cgroup-driver",
+			Sources:     cli.EnvVars("PLUGIN_CGROUP_DRIVER"),
+			Usage:       "docker daemon cgroup driver (cgroupfs, systemd)",
+			Destination: &settings.Daemon.CgroupDriver,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "daemon.config",
+			Sources:     cli.EnvVars("PLUGIN_DAEMON_CONFIG"),
+			Usage:       "raw JSON docker daemon config merged on top of the generated daemon config",
+			Destination: &settings.Daemon.ConfigOverride,
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "daemon.max-concurrent-uploads",
 			Sources:     cli.EnvVars("PLUGIN_MAX_CONCURRENT_UPLOADS"),
@@ -175,6 +289,76 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Daemon.MaxConcurrentUploads,
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "mode",
+			Sources:     cli.EnvVars("PLUGIN_MODE"),
+			Usage:       "build mode to use (build, bake, cleanup, promote, push-by-digest, merge)",
+			Value:       ModeBuild,
+			Destination: &settings.Mode,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "promote.source",
+			Sources:     cli.EnvVars("PLUGIN_PROMOTE_SOURCE"),
+			Usage:       "source image to retag in promote mode",
+			Destination: &settings.PromoteSource,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "digests.dir",
+			Sources:     cli.EnvVars("PLUGIN_DIGESTS_DIR"),
+			Usage:       "directory to record and merge image digests in push-by-digest and merge mode",
+			Value:       ".digests",
+			Destination: &settings.DigestsDir,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "result-file",
+			Sources:     cli.EnvVars("PLUGIN_RESULT_FILE"),
+			Usage:       "file to write the build result with image digest, tags and platforms to",
+			Destination: &settings.ResultFile,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "builds",
+			Sources:     cli.EnvVars("PLUGIN_BUILDS"),
+			Usage:       "list of build definitions sharing the docker daemon and builder",
+			Destination: &settings.BuildsRaw,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "builds.parallel",
+			Sources:     cli.EnvVars("PLUGIN_BUILDS_PARALLEL"),
+			Usage:       "max number of build definitions to run in parallel",
+			Value:       1,
+			Destination: &settings.BuildsParallel,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "bake.files",
+			Sources:     cli.EnvVars("PLUGIN_BAKE_FILES"),
+			Usage:       "bake definition files to use in bake mode",
+			Destination: &settings.Bake.Files,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "bake.targets",
+			Sources:     cli.EnvVars("PLUGIN_BAKE_TARGETS"),
+			Usage:       "bake targets or groups to build in bake mode",
+			Destination: &settings.Bake.Targets,
+			Category:    category,
+		},
+		&plugin_cli.StringSliceFlag{
+			Name:        "bake.set",
+			Sources:     cli.EnvVars("PLUGIN_BAKE_SET"),
+			Usage:       "additional bake target overrides",
+			Destination: &settings.Bake.Set,
+			Config: plugin_cli.StringSliceConfig{
+				Delimiter:    ",",
+				EscapeString: "\\",
+			},
+			Category: category,
+		},
 		&cli.StringFlag{
 			Name:        "containerfile",
 			Sources:     cli.EnvVars("PLUGIN_CONTAINERFILE"),
@@ -226,6 +410,100 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Build.TagsSuffix,
 			Category:    category,
 		},
+		&cli.BoolFlag{
+			Name:        "skip-unchanged",
+			Sources:     cli.EnvVars("PLUGIN_SKIP_UNCHANGED"),
+			Usage:       "skip the build if no relevant files changed since the previous commit",
+			Value:       false,
+			Destination: &settings.SkipUnchanged,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "skip-unchanged.paths",
+			Sources:     cli.EnvVars("PLUGIN_SKIP_UNCHANGED_PATHS"),
+			Usage:       "path filters of relevant changes, defaults to the build context and Containerfile",
+			Destination: &settings.SkipUnchangedPaths,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "tags.prefix",
+			Sources:     cli.EnvVars("PLUGIN_TAG_PREFIX"),
+			Usage:       "only build git tags with the given prefix and strip it for semver tags",
+			Destination: &settings.TagPrefix,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.sanitize",
+			Sources:     cli.EnvVars("PLUGIN_SANITIZE_TAGS"),
+			Usage:       "sanitize invalid repository names and tags instead of failing",
+			Value:       false,
+			Destination: &settings.SanitizeRefs,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.immutable",
+			Sources:     cli.EnvVars("PLUGIN_IMMUTABLE_TAGS"),
+			Usage:       "protect existing semver tags in the registry from being overwritten",
+			Value:       false,
+			Destination: &settings.Immutable.Enabled,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "tags.immutable.policy",
+			Sources:     cli.EnvVars("PLUGIN_IMMUTABLE_TAGS_POLICY"),
+			Usage:       "action if an immutable tag already exists, 'fail' or 'skip'",
+			Value:       ImmutablePolicyFail,
+			Destination: &settings.Immutable.Policy,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.auto.branch",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_BRANCH"),
+			Usage:       "generate a sanitized branch name tag",
+			Value:       false,
+			Destination: &settings.TagStrategies.Branch,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.auto.sha",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_SHA"),
+			Usage:       "generate a short commit SHA tag",
+			Value:       false,
+			Destination: &settings.TagStrategies.SHA,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.auto.sha-long",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_SHA_LONG"),
+			Usage:       "generate a full commit SHA tag",
+			Value:       false,
+			Destination: &settings.TagStrategies.SHALong,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.auto.pull-request",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_PULL_REQUEST"),
+			Usage:       "generate a pr-<number> tag for pull requests",
+			Value:       false,
+			Destination: &settings.TagStrategies.PullRequest,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "tags.auto.timestamp",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_TIMESTAMP"),
+			Usage:       "generate a build timestamp tag",
+			Value:       false,
+			Destination: &settings.TagStrategies.Timestamp,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "tags.auto.timestamp-format",
+			Sources:     cli.EnvVars("PLUGIN_AUTO_TAG_TIMESTAMP_FORMAT"),
+			Usage:       "go time layout of the build timestamp tag",
+			Value:       defaultTimestampFormat,
+			Destination: &settings.TagStrategies.TimestampFormat,
+			Category:    category,
+		},
 		&cli.StringSliceFlag{
 			Name: "extra.tags",
 			Sources: cli.ValueSourceChain{
@@ -252,6 +530,13 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			Destination: &settings.Build.ArgsEnv,
 			Category:    category,
 		},
+		&cli.StringSliceFlag{
+			Name:        "args-masked",
+			Sources:     cli.EnvVars("PLUGIN_BUILD_ARGS_MASKED"),
+			Usage:       "custom build arguments whose values are masked in the output",
+			Destination: &settings.ArgsMasked,
+			Category:    category,
+		},
 		&cli.BoolFlag{
 			Name:        "quiet",
 			Sources:     cli.EnvVars("PLUGIN_QUIET"),
@@ -285,11 +570,73 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			},
 			Category: category,
 		},
-		&cli.StringFlag{
+		&plugin_cli.StringSliceFlag{
 			Name:        "cache-to",
 			Sources:     cli.EnvVars("PLUGIN_CACHE_TO"),
-			Usage:       "cache destination for the build cache",
+			Usage:       "cache destinations for the build cache",
 			Destination: &settings.Build.CacheTo,
+			Config: plugin_cli.StringSliceConfig{
+				Delimiter:    ",",
+				EscapeString: "\\",
+			},
+			Category: category,
+		},
+		&cli.StringFlag{
+			Name:        "cache.type",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_TYPE"),
+			Usage:       "structured build cache type (registry, local, inline, s3)",
+			Destination: &settings.Build.Cache.Type,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "cache.mode",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_MODE"),
+			Usage:       "structured build cache export mode (min, max)",
+			Destination: &settings.Build.Cache.Mode,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "cache.compression",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_COMPRESSION"),
+			Usage:       "structured build cache compression (gzip, estargz, zstd, uncompressed)",
+			Destination: &settings.Build.Cache.Compression,
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "cache.ref",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_REF"),
+			Usage:       "structured build cache reference",
+			Destination: &settings.Build.Cache.Ref,
+			Category:    category,
+		},
+		&cli.StringSliceFlag{
+			Name:        "cache.options",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_OPTIONS"),
+			Usage:       "additional structured build cache backend options",
+			Destination: &settings.Build.Cache.Options,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "cache.auto",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_AUTO"),
+			Usage:       "derive the structured build cache reference from the git branch",
+			Value:       false,
+			Destination: &settings.Build.Cache.Auto,
+			Category:    category,
+		},
+		&cli.BoolFlag{
+			Name:        "cache.rotate",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_ROTATE"),
+			Usage:       "export the local build cache to a fresh directory and swap it after a successful build",
+			Value:       false,
+			Destination: &settings.Build.Cache.Rotate,
+			Category:    category,
+		},
+		&cli.IntFlag{
+			Name:        "cache.max-size",
+			Sources:     cli.EnvVars("PLUGIN_CACHE_MAX_SIZE"),
+			Usage:       "size cap in MiB for the rotated local build cache",
+			Destination: &settings.Build.Cache.MaxSize,
 			Category:    category,
 		},
 		&cli.BoolFlag{
@@ -339,6 +686,14 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			DefaultText: "$DOCKER_PASSWORD",
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "docker.password-file",
+			Sources:     cli.EnvVars("PLUGIN_PASSWORD_FILE", "DOCKER_PASSWORD_FILE"),
+			Usage:       "path to a file containing the password for registry authentication",
+			Destination: &settings.PasswordFile,
+			DefaultText: "$DOCKER_PASSWORD_FILE",
+			Category:    category,
+		},
 		&cli.StringFlag{
 			Name:        "docker.email",
 			Sources:     cli.EnvVars("PLUGIN_EMAIL", "DOCKER_EMAIL"),
@@ -355,6 +710,28 @@ func Flags(settings *Settings, category string) []cli.Flag {
 			DefaultText: "$DOCKER_REGISTRY_CONFIG",
 			Category:    category,
 		},
+		&cli.StringFlag{
+			Name:        "registry.config-file",
+			Sources:     cli.EnvVars("PLUGIN_REGISTRY_CONFIG_FILE", "DOCKER_REGISTRY_CONFIG_FILE"),
+			Usage:       "path to the registry credentials store file",
+			Destination: &settings.RegistryConfigFile,
+			DefaultText: "$DOCKER_REGISTRY_CONFIG_FILE",
+			Category:    category,
+		},
+		&cli.StringFlag{
+			Name:        "registries",
+			Sources:     cli.EnvVars("PLUGIN_REGISTRIES"),
+			Usage:       "list of additional registries to authenticate with",
+			Destination: &settings.RegistriesRaw,
+			Category:    category,
+		},
+		&plugin_cli.StringMapFlag{
+			Name:        "registry.credential-helpers",
+			Sources:     cli.EnvVars("PLUGIN_CREDENTIAL_HELPERS"),
+			Usage:       "credential helpers to use for registry authentication by registry host",
+			Destination: &settings.CredHelpers,
+			Category:    category,
+		},
 		&cli.BoolFlag{
 			Name:        "no-cache",
 			Sources:     cli.EnvVars("PLUGIN_NO_CACHE"),
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
 	"github.com/urfave/cli/v3"
 )
 
@@ -122,6 +123,46 @@ func TestCacheFromFlag(t *testing.T) {
 	}
 }
 
+func TestCacheToFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		envs map[string]string
+		want []string
+	}{
+		{
+			name: "multiple destinations",
+			envs: map[string]string{
+				"PLUGIN_CACHE_TO": `type=registry\,ref=example\,mode=max,type=local\,dest=/cache`,
+			},
+			want: []string{
+				"type=registry,ref=example,mode=max",
+				"type=local,dest=/cache",
+			},
+		},
+		{
+			name: "unescaped single destination",
+			envs: map[string]string{
+				"PLUGIN_CACHE_TO": "type=registry,ref=example/repo:cache,mode=max",
+			},
+			want: []string{
+				"type=registry,ref=example/repo:cache,mode=max",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.envs {
+				t.Setenv(key, value)
+			}
+
+			got := setupPluginTest(t)
+
+			assert.ElementsMatch(t, tt.want, docker.JoinCacheEntries(got.Settings.Build.CacheTo))
+		})
+	}
+}
+
 func TestBuildArgsFlag(t *testing.T) {
 	tests := []struct {
 		name string
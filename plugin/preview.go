@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+const quayExpiresLabel = "quay.expires-after"
+
+var (
+	ErrInvalidPreview = errors.New("invalid preview")
+	ErrCleanupFailed  = errors.New("preview cleanup failed")
+
+	previewExpiresPattern = regexp.MustCompile(`^[1-9][0-9]*[hdw]$`)
+)
+
+// Preview defines the settings of preview images for pull requests.
+type Preview struct {
+	Enabled bool
+	Expires string
+}
+
+// previewActive returns true if a preview image is built for a pull request.
+func (p *Plugin) previewActive() bool {
+	return p.Settings.Preview.Enabled && p.Metadata.Curr.PullRequest > 0
+}
+
+// validatePreview checks the expiry of preview images.
+func (p *Plugin) validatePreview() error {
+	if expires := p.Settings.Preview.Expires; expires != "" && !previewExpiresPattern.MatchString(expires) {
+		return fmt.Errorf("%w: expiry %s must be a number followed by h, d or w", ErrInvalidPreview, expires)
+	}
+
+	return nil
+}
+
+// applyPreview replaces the tags of the build with the preview tag of the pull request.
+// Extra tags are dropped to not overwrite other images with the preview image.
+func (p *Plugin) applyPreview(build *docker.Build) {
+	if !p.previewActive() {
+		return
+	}
+
+	tag := PullRequestTag(p.Metadata.Curr.PullRequest)
+
+	log.Info().Msgf("build preview image %s:%s", build.Repo, tag)
+
+	build.Tags = []string{tag}
+	build.ExtraTags = nil
+}
+
+// PreviewLabels returns the registry-specific expiry labels of preview images.
+func (p *Plugin) PreviewLabels() []string {
+	if !p.previewActive() || p.Settings.Preview.Expires == "" {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf("%s=%s", quayExpiresLabel, p.Settings.Preview.Expires)}
+}
+
+// cleanupPreview deletes the preview tag of the pull request from all repositories through
+// the registry HTTP API.
+func (p *Plugin) cleanupPreview(ctx context.Context) error {
+	tag := PullRequestTag(p.Metadata.Curr.PullRequest)
+	errs := make([]error, 0)
+
+	for _, repo := range p.previewRepos() {
+		username, password := p.registryCredentials(repo)
+		client := docker.NewRegistryClient(username, password)
+
+		err := client.DeleteTag(ctx, repo, tag)
+		if errors.Is(err, docker.ErrManifestNotFound) {
+			log.Info().Msgf("preview image %s:%s not found", repo, tag)
+
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		log.Info().Msgf("deleted preview image %s:%s", repo, tag)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrCleanupFailed, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// previewRepos returns the repositories of all builds and additional registries.
+func (p *Plugin) previewRepos() []string {
+	repos := make([]string, 0)
+
+	add := func(repo string) {
+		if repo != "" && !slices.Contains(repos, repo) {
+			repos = append(repos, repo)
+		}
+	}
+
+	for _, build := range p.builds() {
+		add(build.Repo)
+
+		for _, repo := range RegistryRepos(p.Settings.Registries, build) {
+			add(repo)
+		}
+	}
+
+	return repos
+}
+
+// registryCredentials returns the credentials of the configured registry matching the
+// registry host of the repository.
+func (p *Plugin) registryCredentials(repo string) (string, string) {
+	host, _ := docker.ParseRepository(repo)
+	host = docker.NormalizeRegistry(host)
+
+	registries := append([]docker.Registry{p.Settings.Registry}, p.Settings.Registries...)
+
+	for _, registry := range registries {
+		if registry.Password != "" && docker.NormalizeRegistry(registry.Address) == host {
+			return registry.Username, registry.Password
+		}
+	}
+
+	return "", ""
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func newPreviewPlugin(pullRequest int, preview Preview) *Plugin {
+	return &Plugin{
+		Plugin: &plugin_base.Plugin{
+			Metadata: plugin_base.Metadata{
+				Curr: plugin_base.Commit{PullRequest: pullRequest},
+			},
+		},
+		Settings: &Settings{
+			Preview: preview,
+			Build: docker.Build{
+				Repo:      "quay.io/octocat/app",
+				Tags:      []string{"latest"},
+				ExtraTags: []string{"ghcr.io/octocat/app:latest"},
+			},
+		},
+	}
+}
+
+func TestApplyPreview(t *testing.T) {
+	tests := []struct {
+		name          string
+		pullRequest   int
+		preview       Preview
+		wantTags      []string
+		wantExtraTags []string
+		wantLabels    []string
+		wantErr       bool
+	}{
+		{
+			name:          "pull request",
+			pullRequest:   42,
+			preview:       Preview{Enabled: true, Expires: "1w"},
+			wantTags:      []string{"pr-42"},
+			wantLabels:    []string{"quay.expires-after=1w"},
+			wantExtraTags: nil,
+		},
+		{
+			name:          "no pull request",
+			preview:       Preview{Enabled: true, Expires: "1w"},
+			wantTags:      []string{"latest"},
+			wantExtraTags: []string{"ghcr.io/octocat/app:latest"},
+			wantLabels:    []string{},
+		},
+		{
+			name:          "preview disabled",
+			pullRequest:   42,
+			preview:       Preview{Expires: "1w"},
+			wantTags:      []string{"latest"},
+			wantExtraTags: []string{"ghcr.io/octocat/app:latest"},
+			wantLabels:    []string{},
+		},
+		{
+			name:        "invalid expiry",
+			pullRequest: 42,
+			preview:     Preview{Enabled: true, Expires: "7 days"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPreviewPlugin(tt.pullRequest, tt.preview)
+
+			err := p.validatePreview()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidPreview)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			p.applyPreview(&p.Settings.Build)
+			assert.Equal(t, tt.wantTags, p.Settings.Build.Tags)
+			assert.Equal(t, tt.wantExtraTags, p.Settings.Build.ExtraTags)
+			assert.Equal(t, tt.wantLabels, p.PreviewLabels())
+		})
+	}
+}
+
+func TestApplyPreviewMatrix(t *testing.T) {
+	p := newPreviewPlugin(42, Preview{Enabled: true, Expires: "1w"})
+
+	builds, err := ParseBuilds(`[
+		{"name": "api", "repo": "quay.io/octocat/api", "tags": ["1.0"], "extra_tags": ["ghcr.io/octocat/api:1.0"]},
+		{"name": "web", "repo": "quay.io/octocat/web"}
+	]`, p.Settings.Build)
+	assert.NoError(t, err)
+
+	p.Settings.Builds = builds
+
+	for _, build := range p.builds() {
+		p.applyPreview(build)
+
+		assert.Equal(t, []string{"pr-42"}, build.Tags)
+		assert.Empty(t, build.ExtraTags)
+	}
+}
+
+func TestPreviewRepos(t *testing.T) {
+	p := newPreviewPlugin(42, Preview{Enabled: true})
+	p.Settings.Registries = []docker.Registry{
+		{Address: "ghcr.io", Repo: "ghcr.io/octocat/app"},
+		{Address: "harbor.example.com"},
+		{Address: "quay.io", Repo: "quay.io/octocat/app"},
+	}
+
+	assert.Equal(t, []string{"quay.io/octocat/app", "ghcr.io/octocat/app"}, p.previewRepos())
+
+	p.Settings.Builds = []docker.Build{
+		{Name: "api", Repo: "quay.io/octocat/api"},
+		{Name: "web", Repo: "quay.io/octocat/web"},
+	}
+
+	assert.Equal(t, []string{
+		"quay.io/octocat/api", "ghcr.io/octocat/api", "quay.io/octocat/web", "ghcr.io/octocat/web",
+	}, p.previewRepos())
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	p := newPreviewPlugin(42, Preview{Enabled: true})
+	p.Settings.Registry = docker.Registry{
+		Address:  "https://index.docker.io/v1/",
+		Username: "hub",
+		Password: "hub-secret",
+	}
+	p.Settings.Registries = []docker.Registry{
+		{Address: "quay.io", Username: "quay", Password: "quay-secret"},
+	}
+
+	tests := []struct {
+		repo         string
+		wantUsername string
+		wantPassword string
+	}{
+		{"octocat/app", "hub", "hub-secret"},
+		{"quay.io/octocat/app", "quay", "quay-secret"},
+		{"ghcr.io/octocat/app", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			username, password := p.registryCredentials(tt.repo)
+			assert.Equal(t, tt.wantUsername, username)
+			assert.Equal(t, tt.wantPassword, password)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+var ErrInvalidPromote = errors.New("invalid promote settings")
+
+// digestFunc resolves the digest of the image created from the sources.
+type digestFunc func(ctx context.Context, sources []string) (string, error)
+
+// validatePromote renders and validates the source image of promote mode.
+func (p *Plugin) validatePromote() error {
+	sources, err := RenderTagTemplates([]string{p.Settings.PromoteSource}, p.TagTemplateData())
+	if err != nil {
+		return err
+	}
+
+	p.Settings.PromoteSource = sources[0]
+
+	if err := docker.ValidateReference(p.Settings.PromoteSource); err != nil {
+		return fmt.Errorf("%w: source image: %w", ErrInvalidPromote, err)
+	}
+
+	if len(p.Settings.Build.Refs()) == 0 {
+		return fmt.Errorf("%w: no target tags", ErrInvalidPromote)
+	}
+
+	return nil
+}
+
+// promote retags the source image with all target tags.
+func (p *Plugin) promote(ctx context.Context, homeDir string) error {
+	return p.createImage(ctx, homeDir, []string{p.Settings.PromoteSource}, p.sourceDigest)
+}
+
+// sourceDigest returns the digest of the promote source image from the registry.
+func (p *Plugin) sourceDigest(ctx context.Context, sources []string) (string, error) {
+	source := sources[0]
+
+	if _, digest, ok := strings.Cut(source, "@"); ok {
+		return digest, nil
+	}
+
+	repo, tag, ok := docker.SplitReference(source)
+	if !ok {
+		tag = "latest"
+	}
+
+	username, password := p.registryCredentials(repo)
+
+	digest, err := docker.NewRegistryClient(username, password).Digest(ctx, repo, tag)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve digest of %s: %w", source, err)
+	}
+
+	return digest, nil
+}
+
+// createImage creates an image from the sources with all target tags using buildx imagetools.
+// The docker daemon is not required, registry credentials are written to the docker config directly.
+func (p *Plugin) createImage(ctx context.Context, homeDir string, sources []string, digest digestFunc) error {
+	configDir, err := p.setupDockerConfig(homeDir)
+	if err != nil {
+		return fmt.Errorf("error writing docker config: %w", err)
+	}
+
+	defer p.cleanupDockerConfig(configDir)
+
+	if err := p.writeRegistryAuths(filepath.Join(configDir, dockerConfigFile)); err != nil {
+		return fmt.Errorf("error writing registry credentials: %w", err)
+	}
+
+	if err := p.protectImmutableImage(ctx, sources, digest); err != nil {
+		return err
+	}
+
+	if len(p.Settings.Build.Refs()) == 0 {
+		log.Info().Msg("skip image creation, all target tags already exist")
+
+		return nil
+	}
+
+	imagetools := docker.Imagetools{
+		Sources: sources,
+		Refs:    p.Settings.Build.Refs(),
+		Dryrun:  p.Settings.Build.Dryrun,
+	}
+
+	return p.runCmd(imagetools.Create())
+}
+
+// writeRegistryAuths writes the credentials of all registries to the docker config at path.
+func (p *Plugin) writeRegistryAuths(path string) error {
+	conf, err := docker.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	registries := append([]docker.Registry{p.Settings.Registry}, p.Settings.Registries...)
+
+	for _, registry := range registries {
+		if registry.Password == "" {
+			continue
+		}
+
+		conf.SetAuth(registry.Address, registry.Username, registry.Password)
+	}
+
+	return conf.Write(path)
+}
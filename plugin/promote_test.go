@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestValidatePromote(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		tags    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "source tag",
+			source: "quay.io/octocat/app:sha-abc",
+			tags:   []string{"1.2.3", "latest"},
+			want:   "quay.io/octocat/app:sha-abc",
+		},
+		{
+			name:   "source template",
+			source: "quay.io/octocat/app:{{ .Commit.SHA | trunc 7 }}",
+			tags:   []string{"latest"},
+			want:   "quay.io/octocat/app:d2f8c1a",
+		},
+		{
+			name:    "invalid source",
+			source:  "quay.io/OctoCat/app:sha-abc",
+			tags:    []string{"latest"},
+			wantErr: true,
+		},
+		{
+			name:    "no target tags",
+			source:  "quay.io/octocat/app:sha-abc",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				Plugin: &plugin_base.Plugin{
+					Metadata: plugin_base.Metadata{
+						Curr: plugin_base.Commit{SHA: "d2f8c1a9e4b7f0c3"},
+					},
+				},
+				Settings: &Settings{
+					PromoteSource: tt.source,
+					Build:         docker.Build{Repo: "quay.io/octocat/app", Tags: tt.tags},
+				},
+			}
+
+			err := p.validatePromote()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidPromote)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, p.Settings.PromoteSource)
+		})
+	}
+}
+
+func TestWriteRegistryAuths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"credHelpers": {"ghcr.io": "pass"}}`), 0o600))
+
+	p := &Plugin{
+		Settings: &Settings{
+			Registry: docker.Registry{Address: "https://index.docker.io/v1/", Username: "octocat", Password: "secret"},
+			Registries: []docker.Registry{
+				{Address: "quay.io", Username: "robot", Password: "token"},
+			},
+		},
+	}
+
+	assert.NoError(t, p.writeRegistryAuths(path))
+
+	conf, err := docker.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ghcr.io": "pass"}, conf.CredHelpers)
+	assert.Equal(t, map[string]docker.AuthConfig{
+		"https://index.docker.io/v1/": {Auth: "b2N0b2NhdDpzZWNyZXQ="},
+		"quay.io":                     {Auth: "cm9ib3Q6dG9rZW4="},
+	}, conf.Auths)
+}
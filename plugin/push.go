@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PushPolicy defines the events, branches and refs images are pushed for. Empty lists
+// do not restrict pushing.
+type PushPolicy struct {
+	Events   []string
+	Branches []string
+	Refs     []string
+}
+
+// Allows returns true if images should be pushed for the given event, branch and ref. If
+// pushing is not allowed, the reason is returned as well. The branch filter is not applied
+// to tag events.
+func (pp PushPolicy) Allows(event, branch, ref string) (bool, string) {
+	if len(pp.Events) > 0 && !slices.Contains(pp.Events, event) {
+		return false, fmt.Sprintf("event %s does not match push events %v", event, pp.Events)
+	}
+
+	if len(pp.Branches) > 0 && !strings.HasPrefix(ref, tagRefPrefix) && !matchAny(pp.Branches, branch) {
+		return false, fmt.Sprintf("branch %s does not match push branches %v", branch, pp.Branches)
+	}
+
+	if len(pp.Refs) > 0 && !matchAny(pp.Refs, ref) {
+		return false, fmt.Sprintf("ref %s does not match push refs %v", ref, pp.Refs)
+	}
+
+	return true, ""
+}
+
+// applyPushPolicy disables pushing for all builds if the push policy does not allow
+// pushing for the current pipeline.
+func (p *Plugin) applyPushPolicy() {
+	ok, reason := p.Settings.PushPolicy.Allows(
+		p.Metadata.Pipeline.Event,
+		p.Metadata.Curr.Branch,
+		p.Metadata.Curr.Ref,
+	)
+	if ok {
+		return
+	}
+
+	log.Info().Msgf("disable push, %s", reason)
+
+	p.Settings.Build.Dryrun = true
+
+	for _, build := range p.builds() {
+		build.Dryrun = true
+	}
+}
+
+// matchAny returns true if the value matches one of the glob patterns.
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
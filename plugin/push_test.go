@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestPushPolicyAllows(t *testing.T) {
+	policy := PushPolicy{
+		Events:   []string{"push", "tag"},
+		Branches: []string{"main", "release/*"},
+	}
+
+	tests := []struct {
+		name   string
+		policy PushPolicy
+		event  string
+		branch string
+		ref    string
+		want   bool
+	}{
+		{
+			name:   "no restrictions",
+			event:  "pull_request",
+			branch: "main",
+			ref:    "refs/pull/1/head",
+			want:   true,
+		},
+		{
+			name:   "default branch push",
+			policy: policy,
+			event:  "push",
+			branch: "main",
+			ref:    "refs/heads/main",
+			want:   true,
+		},
+		{
+			name:   "release branch push",
+			policy: policy,
+			event:  "push",
+			branch: "release/1.0",
+			ref:    "refs/heads/release/1.0",
+			want:   true,
+		},
+		{
+			name:   "tag ignores branch filter",
+			policy: policy,
+			event:  "tag",
+			ref:    "refs/tags/v1.0.0",
+			want:   true,
+		},
+		{
+			name:   "pull request",
+			policy: policy,
+			event:  "pull_request",
+			branch: "main",
+			ref:    "refs/pull/1/head",
+		},
+		{
+			name:   "feature branch",
+			policy: policy,
+			event:  "push",
+			branch: "feature/foo",
+			ref:    "refs/heads/feature/foo",
+		},
+		{
+			name:   "ref filter",
+			policy: PushPolicy{Refs: []string{"refs/tags/v*"}},
+			event:  "tag",
+			ref:    "refs/tags/api/v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.policy.Allows(tt.event, tt.branch, tt.ref)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, reason == "")
+		})
+	}
+}
+
+func TestApplyPushPolicy(t *testing.T) {
+	p := &Plugin{
+		Plugin: &plugin_base.Plugin{
+			Metadata: plugin_base.Metadata{
+				Pipeline: plugin_base.Pipeline{Event: "pull_request"},
+				Curr:     plugin_base.Commit{Branch: "main", Ref: "refs/pull/1/head"},
+			},
+		},
+		Settings: &Settings{
+			PushPolicy: PushPolicy{Events: []string{"push", "tag"}},
+			Builds:     []docker.Build{{Name: "api"}, {Name: "web"}},
+		},
+	}
+
+	p.applyPushPolicy()
+
+	assert.True(t, p.Settings.Build.Dryrun)
+
+	for _, build := range p.Settings.Builds {
+		assert.True(t, build.Dryrun)
+	}
+}
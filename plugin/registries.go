@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+var ErrInvalidRegistries = errors.New("invalid registry definitions")
+
+// RegistryDefinition defines the credentials of an additional registry.
+//
+//nolint:tagliatelle
+type RegistryDefinition struct {
+	Address      string `json:"address"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"password_file"`
+	Email        string `json:"email"`
+	Repo         string `json:"repo"`
+}
+
+// ParseRegistries parses the JSON list of registry definitions. Passwords from files
+// are resolved while parsing.
+func ParseRegistries(raw string) ([]docker.Registry, error) {
+	var defs []RegistryDefinition
+
+	if err := json.Unmarshal([]byte(raw), &defs); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRegistries, err)
+	}
+
+	registries := make([]docker.Registry, 0, len(defs))
+
+	for _, def := range defs {
+		if def.Address == "" {
+			return nil, fmt.Errorf("%w: address is required", ErrInvalidRegistries)
+		}
+
+		if def.Password != "" && def.PasswordFile != "" {
+			return nil, fmt.Errorf("%w: password and password_file of %s are mutually exclusive", ErrInvalidRegistries, def.Address)
+		}
+
+		registry := docker.Registry{
+			Address:  def.Address,
+			Username: def.Username,
+			Password: def.Password,
+			Email:    def.Email,
+			Repo:     def.Repo,
+		}
+
+		if def.PasswordFile != "" {
+			password, err := ReadSecretFile(def.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidRegistries, err)
+			}
+
+			registry.Password = password
+		}
+
+		if registry.Username == "" || registry.Password == "" {
+			return nil, fmt.Errorf("%w: username and password of %s are required", ErrInvalidRegistries, def.Address)
+		}
+
+		registries = append(registries, registry)
+	}
+
+	return registries, nil
+}
+
+// RegistryTags maps the tags of the build to the repositories of all registries with a configured
+// repository.
+func RegistryTags(registries []docker.Registry, build *docker.Build) []string {
+	refs := make([]string, 0)
+
+	for _, repo := range RegistryRepos(registries, build) {
+		for _, tag := range build.Tags {
+			refs = append(refs, fmt.Sprintf("%s:%s", repo, tag))
+		}
+	}
+
+	return refs
+}
+
+// RegistryRepos returns the repositories of the build in all registries with a configured repository.
+// Matrix builds are pushed to the namespace of the registry repository with the name of the build
+// repository, e.g. quay.io/octocat/example and octocat/api result in quay.io/octocat/api.
+func RegistryRepos(registries []docker.Registry, build *docker.Build) []string {
+	repos := make([]string, 0)
+
+	for _, registry := range registries {
+		repo := strings.TrimSuffix(registry.Repo, "/")
+		if repo == "" {
+			continue
+		}
+
+		if build.Name != "" {
+			if build.Repo == "" {
+				continue
+			}
+
+			repo = path.Join(path.Dir(repo), path.Base(build.Repo))
+		}
+
+		repos = append(repos, repo)
+	}
+
+	return repos
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+func TestParseRegistries(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(passwordFile, []byte("file-secret\n"), 0o600))
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []docker.Registry
+		wantErr bool
+	}{
+		{
+			name: "inline password and password file",
+			raw: `[
+				{"address": "quay.io", "username": "octocat", "password": "secret", "repo": "quay.io/octocat/app"},
+				{"address": "harbor.example.com", "username": "robot", "password_file": "` + passwordFile + `"}
+			]`,
+			want: []docker.Registry{
+				{Address: "quay.io", Username: "octocat", Password: "secret", Repo: "quay.io/octocat/app"},
+				{Address: "harbor.example.com", Username: "robot", Password: "file-secret"},
+			},
+		},
+		{
+			name:    "missing address",
+			raw:     `[{"username": "octocat", "password": "secret"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing password",
+			raw:     `[{"address": "quay.io", "username": "octocat"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "password and password file",
+			raw:     `[{"address": "quay.io", "username": "octocat", "password": "a", "password_file": "b"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing password file",
+			raw:     `[{"address": "quay.io", "username": "octocat", "password_file": "/nonexistent"}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegistries(tt.raw)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRegistries)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegistryTags(t *testing.T) {
+	registries := []docker.Registry{
+		{Address: "quay.io", Repo: "quay.io/octocat/app"},
+		{Address: "harbor.example.com"},
+		{Address: "ghcr.io", Repo: "ghcr.io/octocat/app/"},
+	}
+
+	tests := []struct {
+		name  string
+		build docker.Build
+		want  []string
+	}{
+		{
+			name:  "single build",
+			build: docker.Build{Repo: "octocat/app", Tags: []string{"latest", "1.0"}},
+			want: []string{
+				"quay.io/octocat/app:latest",
+				"quay.io/octocat/app:1.0",
+				"ghcr.io/octocat/app:latest",
+				"ghcr.io/octocat/app:1.0",
+			},
+		},
+		{
+			name:  "matrix build",
+			build: docker.Build{Name: "api", Repo: "octocat/api", Tags: []string{"latest"}},
+			want: []string{
+				"quay.io/octocat/api:latest",
+				"ghcr.io/octocat/api:latest",
+			},
+		},
+		{
+			name:  "matrix build without repo",
+			build: docker.Build{Name: "api", Tags: []string{"latest"}},
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RegistryTags(registries, &tt.build))
+		})
+	}
+}
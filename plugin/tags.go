@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+const (
+	shortSHALength         = 7
+	defaultTimestampFormat = "20060102150405"
+	tagRefPrefix           = "refs/tags/"
+)
+
+// TagStrategies defines the additional strategies to generate tags with if auto-tagging is enabled.
+type TagStrategies struct {
+	Branch          bool
+	SHA             bool
+	SHALong         bool
+	PullRequest     bool
+	Timestamp       bool
+	TimestampFormat string
+}
+
+// Enabled returns true if at least one tag strategy is enabled.
+func (s TagStrategies) Enabled() bool {
+	return s.Branch || s.SHA || s.SHALong || s.PullRequest || s.Timestamp
+}
+
+// StrategyTags generates the tags of all enabled tag strategies. Strategies that do not apply
+// to the current event, e.g. pull request tags outside of pull requests, are skipped.
+func (p *Plugin) StrategyTags() ([]string, error) {
+	strategies := p.Settings.TagStrategies
+	commit := p.Metadata.Curr
+	tags := make([]string, 0)
+
+	if strategies.Branch && commit.Branch != "" {
+		tags = append(tags, docker.SanitizeTag(commit.Branch))
+	}
+
+	if strategies.SHA && commit.SHA != "" {
+		tags = append(tags, commit.SHA[:min(len(commit.SHA), shortSHALength)])
+	}
+
+	if strategies.SHALong && commit.SHA != "" {
+		tags = append(tags, commit.SHA)
+	}
+
+	if strategies.PullRequest && commit.PullRequest > 0 {
+		tags = append(tags, PullRequestTag(commit.PullRequest))
+	}
+
+	if strategies.Timestamp {
+		created, err := time.Parse(time.RFC3339, p.Settings.Build.Time)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate timestamp tag: %w", err)
+		}
+
+		format := strategies.TimestampFormat
+		if format == "" {
+			format = defaultTimestampFormat
+		}
+
+		tags = append(tags, docker.SanitizeTag(created.UTC().Format(format)))
+	}
+
+	if suffix := p.Settings.Build.TagsSuffix; suffix != "" {
+		for i, tag := range tags {
+			tags[i] = fmt.Sprintf("%s-%s", tag, suffix)
+		}
+	}
+
+	return tags, nil
+}
+
+// SanitizeRefs sanitizes the repository, tags and extra tags of the build in place.
+func SanitizeRefs(build *docker.Build) {
+	sanitize := func(ref string, fn func(string) string) string {
+		if sanitized := fn(ref); sanitized != ref {
+			log.Info().Msgf("sanitized reference %s to %s", ref, sanitized)
+
+			return sanitized
+		}
+
+		return ref
+	}
+
+	if build.Repo != "" {
+		build.Repo = sanitize(build.Repo, docker.SanitizeRepository)
+	}
+
+	for i, tag := range build.Tags {
+		build.Tags[i] = sanitize(tag, docker.SanitizeTag)
+	}
+
+	for i, ref := range build.ExtraTags {
+		build.ExtraTags[i] = sanitize(ref, docker.SanitizeReference)
+	}
+}
+
+// StripTagPrefix strips the prefix from the tag name of a git tag ref, e.g. refs/tags/api/v1.4.0
+// becomes refs/tags/v1.4.0 for the prefix api/. It returns false if the tag does not match the
+// prefix. Refs other than tags are returned unchanged.
+func StripTagPrefix(ref, prefix string) (string, bool) {
+	tag, ok := strings.CutPrefix(ref, tagRefPrefix)
+	if !ok {
+		return ref, true
+	}
+
+	version, ok := strings.CutPrefix(tag, prefix)
+	if !ok || version == "" {
+		return ref, false
+	}
+
+	return tagRefPrefix + version, true
+}
+
+// PullRequestTag returns the tag for the given pull request number.
+func PullRequestTag(number int) string {
+	return fmt.Sprintf("pr-%d", number)
+}
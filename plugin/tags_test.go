@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestStrategyTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies TagStrategies
+		commit     plugin_base.Commit
+		suffix     string
+		want       []string
+	}{
+		{
+			name:   "no strategies",
+			commit: plugin_base.Commit{Branch: "main", SHA: "d2f8c1a9e4b7f0c3"},
+			want:   []string{},
+		},
+		{
+			name:       "branch",
+			strategies: TagStrategies{Branch: true},
+			commit:     plugin_base.Commit{Branch: "feature/foo"},
+			want:       []string{"feature-foo"},
+		},
+		{
+			name:       "short and long sha",
+			strategies: TagStrategies{SHA: true, SHALong: true},
+			commit:     plugin_base.Commit{SHA: "d2f8c1a9e4b7f0c3"},
+			want:       []string{"d2f8c1a", "d2f8c1a9e4b7f0c3"},
+		},
+		{
+			name:       "pull request",
+			strategies: TagStrategies{PullRequest: true},
+			commit:     plugin_base.Commit{PullRequest: 42},
+			want:       []string{"pr-42"},
+		},
+		{
+			name:       "pull request outside of pull request",
+			strategies: TagStrategies{PullRequest: true},
+			commit:     plugin_base.Commit{Branch: "main"},
+			want:       []string{},
+		},
+		{
+			name:       "timestamp",
+			strategies: TagStrategies{Timestamp: true, TimestampFormat: defaultTimestampFormat},
+			want:       []string{"20240102030405"},
+		},
+		{
+			name:       "timestamp custom format",
+			strategies: TagStrategies{Timestamp: true, TimestampFormat: "2006-01-02T15:04"},
+			want:       []string{"2024-01-02T03-04"},
+		},
+		{
+			name:       "suffix",
+			strategies: TagStrategies{Branch: true, SHA: true},
+			commit:     plugin_base.Commit{Branch: "main", SHA: "d2f8c1a9e4b7f0c3"},
+			suffix:     "arm64",
+			want:       []string{"main-arm64", "d2f8c1a-arm64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				Plugin: &plugin_base.Plugin{
+					Metadata: plugin_base.Metadata{Curr: tt.commit},
+				},
+				Settings: &Settings{
+					TagStrategies: tt.strategies,
+					Build: docker.Build{
+						Time:       "2024-01-02T03:04:05Z",
+						TagsSuffix: tt.suffix,
+					},
+				},
+			}
+
+			got, err := p.StrategyTags()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSanitizeRefs(t *testing.T) {
+	build := &docker.Build{
+		Repo:      "OctoCat/App",
+		Tags:      []string{"latest", "feature/login"},
+		ExtraTags: []string{"quay.io/OctoCat/app:feature/login"},
+	}
+
+	SanitizeRefs(build)
+
+	assert.Equal(t, "octocat/app", build.Repo)
+	assert.Equal(t, []string{"latest", "feature-login"}, build.Tags)
+	assert.Equal(t, []string{"quay.io/octocat/app:feature-login"}, build.ExtraTags)
+	assert.NoError(t, build.ValidateRefs())
+}
+
+func TestStripTagPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		ref    string
+		prefix string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "matching tag",
+			ref:    "refs/tags/api/v1.4.0",
+			prefix: "api/",
+			want:   "refs/tags/v1.4.0",
+			wantOk: true,
+		},
+		{
+			name:   "other component",
+			ref:    "refs/tags/web/v2.0.1",
+			prefix: "api/",
+			want:   "refs/tags/web/v2.0.1",
+		},
+		{
+			name:   "prefix only",
+			ref:    "refs/tags/api/",
+			prefix: "api/",
+			want:   "refs/tags/api/",
+		},
+		{
+			name:   "branch ref",
+			ref:    "refs/heads/main",
+			prefix: "api/",
+			want:   "refs/heads/main",
+			wantOk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := StripTagPrefix(tt.ref, tt.prefix)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
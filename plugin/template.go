@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+const templateDelimiter = "{{"
+
+var (
+	ErrInvalidTagTemplate = errors.New("invalid tag template")
+
+	slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// TagTemplateData is the data tag templates are rendered against.
+type TagTemplateData struct {
+	plugin_base.Metadata
+
+	// Branch is the branch of the current commit.
+	Branch string
+	// Tag is the git tag of the current commit without the refs/tags/ prefix and the tag prefix.
+	Tag string
+	// Build holds the pipeline metadata, e.g. the build number.
+	Build      plugin_base.Pipeline
+	Repository plugin_base.Repository
+	Commit     plugin_base.Commit
+}
+
+// TagTemplateData returns the data to render tag templates against.
+func (p *Plugin) TagTemplateData() TagTemplateData {
+	data := TagTemplateData{
+		Metadata:   p.Metadata,
+		Branch:     p.Metadata.Curr.Branch,
+		Build:      p.Metadata.Pipeline,
+		Repository: p.Metadata.Repository,
+		Commit:     p.Metadata.Curr,
+	}
+
+	ref := p.Metadata.Curr.Ref
+	if p.Settings.TagPrefix != "" {
+		ref, _ = StripTagPrefix(ref, p.Settings.TagPrefix)
+	}
+
+	if tag, ok := strings.CutPrefix(ref, tagRefPrefix); ok {
+		data.Tag = tag
+	}
+
+	if p.Repository != nil {
+		data.Repository = *p.Repository
+	}
+
+	if p.Commit != nil {
+		data.Commit = *p.Commit
+	}
+
+	return data
+}
+
+// RenderTags renders all templated tags and extra tags of the build. Tags without
+// template actions are kept as they are.
+func (p *Plugin) RenderTags(build *docker.Build) error {
+	var err error
+
+	data := p.TagTemplateData()
+
+	if build.Tags, err = RenderTagTemplates(build.Tags, data); err != nil {
+		return err
+	}
+
+	if build.ExtraTags, err = RenderTagTemplates(build.ExtraTags, data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenderTagTemplates renders the given tag templates with the sprig functions and an
+// additional slug function. Empty results are rejected.
+func RenderTagTemplates(tags []string, data any) ([]string, error) {
+	rendered := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if !strings.Contains(tag, templateDelimiter) {
+			rendered = append(rendered, tag)
+
+			continue
+		}
+
+		tmpl, err := template.New("tag").
+			Option("missingkey=error").
+			Funcs(sprig.TxtFuncMap()).
+			Funcs(template.FuncMap{"slug": Slug}).
+			Parse(tag)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTagTemplate, tag, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidTagTemplate, tag, err)
+		}
+
+		result := strings.TrimSpace(buf.String())
+		if result == "" {
+			return nil, fmt.Errorf("%w: %s: rendered to an empty tag", ErrInvalidTagTemplate, tag)
+		}
+
+		rendered = append(rendered, result)
+	}
+
+	return rendered, nil
+}
+
+// Slug converts the given string to lowercase and replaces all non-alphanumeric
+// characters with a dash, e.g. feature/Foo_Bar becomes feature-foo-bar.
+func Slug(s string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+	plugin_base "github.com/thegeeklab/wp-plugin-go/v6/plugin"
+)
+
+func TestRenderTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		extraTags []string
+		want      []string
+		wantExtra []string
+		wantErr   bool
+	}{
+		{
+			name: "plain tags",
+			tags: []string{"latest", "1.0.0"},
+			want: []string{"latest", "1.0.0"},
+		},
+		{
+			name: "branch and commit",
+			tags: []string{"{{ .Branch | slug }}-{{ .Commit.SHA | trunc 8 }}"},
+			want: []string{"feature-foo-bar-d2f8c1a9"},
+		},
+		{
+			name:      "build number",
+			tags:      []string{"latest"},
+			extraTags: []string{"quay.io/octocat/app:{{ .Build.Number }}"},
+			want:      []string{"latest"},
+			wantExtra: []string{"quay.io/octocat/app:42"},
+		},
+		{
+			name: "metadata",
+			tags: []string{"{{ .Repository.Name }}-{{ .Pipeline.Event }}"},
+			want: []string{"app-push"},
+		},
+		{
+			name:    "invalid template",
+			tags:    []string{"{{ .Branch "},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			tags:    []string{"{{ .Unknown }}"},
+			wantErr: true,
+		},
+		{
+			name:    "empty result",
+			tags:    []string{"{{ .Tag }}"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{
+				Plugin: &plugin_base.Plugin{
+					Metadata: plugin_base.Metadata{
+						Repository: plugin_base.Repository{Name: "app"},
+						Pipeline:   plugin_base.Pipeline{Number: 42, Event: "push"},
+						Curr: plugin_base.Commit{
+							SHA:    "d2f8c1a9e4b7f0c3",
+							Ref:    "refs/heads/feature/Foo_Bar",
+							Branch: "feature/Foo_Bar",
+						},
+					},
+				},
+				Settings: &Settings{},
+			}
+			build := &docker.Build{Tags: tt.tags, ExtraTags: tt.extraTags}
+
+			err := p.RenderTags(build)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTagTemplate)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, build.Tags)
+
+			if tt.wantExtra != nil {
+				assert.Equal(t, tt.wantExtra, build.ExtraTags)
+			}
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"main", "main"},
+		{"feature/Foo_Bar", "feature-foo-bar"},
+		{"--release/1.0--", "release-1-0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, Slug(tt.input))
+		})
+	}
+}
+
+func TestRenderTagsLabelsAuto(t *testing.T) {
+	p := &Plugin{
+		Plugin: &plugin_base.Plugin{
+			Metadata: plugin_base.Metadata{
+				Curr: plugin_base.Commit{
+					SHA:    "d2f8c1a9e4b7f0c3",
+					Ref:    "refs/heads/main",
+					Branch: "main",
+				},
+			},
+		},
+		Settings: &Settings{
+			Mode:   ModeBuild,
+			Daemon: docker.Daemon{Disabled: true},
+			Build: docker.Build{
+				Repo:       "quay.io/octocat/app",
+				Tags:       []string{"{{ .Commit.SHA | trunc 8 }}"},
+				LabelsAuto: true,
+			},
+		},
+	}
+
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, []string{"d2f8c1a9"}, p.Settings.Build.Tags)
+	assert.Contains(t, p.Settings.Build.Labels, "org.opencontainers.image.version=d2f8c1a9")
+}
+
+func TestRenderTagsTagPrefix(t *testing.T) {
+	p := &Plugin{
+		Plugin: &plugin_base.Plugin{
+			Metadata: plugin_base.Metadata{
+				Curr: plugin_base.Commit{Ref: "refs/tags/api/v1.4.0"},
+			},
+		},
+		Settings: &Settings{TagPrefix: "api/"},
+	}
+	build := &docker.Build{Tags: []string{"{{ .Tag }}", "{{ .Tag | trimPrefix \"v\" }}-alpine"}}
+
+	assert.NoError(t, p.RenderTags(build))
+	assert.Equal(t, []string{"v1.4.0", "1.4.0-alpine"}, build.Tags)
+}
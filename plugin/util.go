@@ -1,14 +1,26 @@
 package plugin
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/thegeeklab/wp-docker-buildx/docker"
+)
+
+const (
+	credHelperPrefix  = "docker-credential-"
+	worldReadablePerm = 0o004
 )
 
-var errInvalidDockerConfig = errors.New("invalid docker config")
+var (
+	ErrInvalidCredHelper  = errors.New("invalid credential helper")
+	ErrInvalidSecretFile  = errors.New("invalid secret file")
+	ErrConflictingSecrets = errors.New("conflicting secret sources")
+)
 
 func GetContainerIP() (string, error) {
 	netInterfaceAddrList, err := net.InterfaceAddrs()
@@ -26,35 +38,117 @@ func GetContainerIP() (string, error) {
 	return "", nil
 }
 
-func WriteDockerConf(path, conf string) error {
-	var jsonData interface{}
-	if err := json.Unmarshal([]byte(conf), &jsonData); err != nil {
-		return fmt.Errorf("%w: %w", errInvalidDockerConfig, err)
+// ReadSecretFile reads a secret from the given file and trims trailing newlines.
+// Missing and world-readable files are rejected.
+func ReadSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s does not exist", ErrInvalidSecretFile, path)
+		}
+
+		return "", fmt.Errorf("%w: %w", ErrInvalidSecretFile, err)
+	}
+
+	if info.IsDir() {
+		return "", fmt.Errorf("%w: %s is a directory", ErrInvalidSecretFile, path)
+	}
+
+	if info.Mode().Perm()&worldReadablePerm != 0 {
+		return "", fmt.Errorf("%w: %s is world-readable (mode %s)", ErrInvalidSecretFile, path, info.Mode().Perm())
 	}
 
-	jsonBytes, err := json.Marshal(jsonData)
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("%w: %w", errInvalidDockerConfig, err)
+		return "", fmt.Errorf("%w: %w", ErrInvalidSecretFile, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// ReadSecretFiles reads the settings provided as files. Setting both, the inline value
+// and the file, is rejected.
+func (p *Plugin) ReadSecretFiles() error {
+	secrets := []struct {
+		name  string
+		file  string
+		value *string
+	}{
+		{"password", p.Settings.PasswordFile, &p.Settings.Registry.Password},
+		{"registry config", p.Settings.RegistryConfigFile, &p.Settings.Registry.Config},
+		{"buildkit config", p.Settings.BuildkitConfigFile, &p.Settings.BuildkitConfig},
+	}
+
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+
+		if *secret.value != "" {
+			return fmt.Errorf("%w: %s and %s file are mutually exclusive", ErrConflictingSecrets, secret.name, secret.name)
+		}
+
+		value, err := ReadSecretFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("cannot read %s file: %w", secret.name, err)
+		}
+
+		*secret.value = value
 	}
 
-	err = os.WriteFile(path, jsonBytes, strictFilePerm)
+	return nil
+}
+
+// WriteDockerConf merges the registry config and the credential helpers into the docker config
+// at the given path. Values from the registry config take precedence over an existing config,
+// credential helpers take precedence over both.
+func WriteDockerConf(path, conf string, credHelpers map[string]string) error {
+	dockerConf, err := docker.LoadConfig(path)
 	if err != nil {
 		return err
 	}
 
+	if conf != "" {
+		registryConf, err := docker.ParseConfig([]byte(conf))
+		if err != nil {
+			return err
+		}
+
+		dockerConf.Merge(registryConf)
+	}
+
+	dockerConf.Merge(&docker.Config{CredHelpers: credHelpers})
+
+	if err := dockerConf.Validate(); err != nil {
+		return err
+	}
+
+	return dockerConf.Write(path)
+}
+
+// ValidateCredHelpers checks that the credential helper executables exist in the PATH.
+func ValidateCredHelpers(helpers map[string]string) error {
+	for host, helper := range helpers {
+		if helper == "" {
+			return fmt.Errorf("%w: empty credential helper for %s", ErrInvalidCredHelper, host)
+		}
+
+		if _, err := exec.LookPath(credHelperPrefix + helper); err != nil {
+			return fmt.Errorf("%w: %s for %s: %w", ErrInvalidCredHelper, credHelperPrefix+helper, host, err)
+		}
+	}
+
 	return nil
 }
 
-func (p *Plugin) GenerateLabels() []string {
+func (p *Plugin) GenerateLabels(build *docker.Build) []string {
 	l := make([]string, 0)
 
 	// As described in https://github.com/opencontainers/image-spec/blob/main/annotations.md
-	l = append(l, fmt.Sprintf("org.opencontainers.image.created=%s", p.Settings.Build.Time))
+	l = append(l, fmt.Sprintf("org.opencontainers.image.created=%s", build.Time))
 
-	if p.Settings != nil {
-		if tags := p.Settings.Build.Tags; len(tags) > 0 {
-			l = append(l, fmt.Sprintf("org.opencontainers.image.version=%s", tags[len(tags)-1]))
-		}
+	if tags := build.Tags; len(tags) > 0 {
+		l = append(l, fmt.Sprintf("org.opencontainers.image.version=%s", tags[len(tags)-1]))
 	}
 
 	if p.Repository != nil && p.Repository.URL != "" {
@@ -66,5 +160,5 @@ func (p *Plugin) GenerateLabels() []string {
 		l = append(l, fmt.Sprintf("org.opencontainers.image.revision=%s", p.Commit.SHA))
 	}
 
-	return l
+	return append(l, p.PreviewLabels()...)
 }
@@ -1,7 +1,6 @@
 package plugin
 
 import (
-	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -13,29 +12,58 @@ import (
 
 func TestWriteDockerConf(t *testing.T) {
 	tests := []struct {
-		name    string
-		conf    string
-		wantErr bool
+		name        string
+		existing    string
+		conf        string
+		credHelpers map[string]string
+		want        string
+		wantErr     bool
 	}{
 		{
-			name:    "valid json config",
-			conf:    `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
-			wantErr: false,
+			name: "valid json config",
+			conf: `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
+			want: `{"auths":{"registry.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
 		},
 		{
 			name:    "invalid json config",
 			conf:    `{"auths":invalid}`,
 			wantErr: true,
 		},
+		{
+			name:    "invalid auth encoding",
+			conf:    `{"auths":{"registry.example.com":{"auth":"not-base64"}}}`,
+			wantErr: true,
+		},
+		{
+			name:        "credential helpers only",
+			credHelpers: map[string]string{"123456789.dkr.ecr.eu-central-1.amazonaws.com": "ecr-login"},
+			want:        `{"credHelpers":{"123456789.dkr.ecr.eu-central-1.amazonaws.com":"ecr-login"}}`,
+		},
+		{
+			name:        "merge with existing config",
+			existing:    `{"auths":{"a.example.com":{"auth":"dXNlcjpvbGQ="}},"credHelpers":{"gcr.io":"gcloud"},"psFormat":"table"}`,
+			conf:        `{"auths":{"a.example.com":{"auth":"dXNlcjpuZXc="},"b.example.com":{"auth":"dXNlcjpwYXNz"}}}`,
+			credHelpers: map[string]string{"gcr.io": "pass"},
+			want: `{
+				"auths":{"a.example.com":{"auth":"dXNlcjpuZXc="},"b.example.com":{"auth":"dXNlcjpwYXNz"}},
+				"credHelpers":{"gcr.io":"pass"},
+				"psFormat":"table"
+			}`,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tmpFile := filepath.Join(t.TempDir(), "config.json")
+			tmpFile := filepath.Join(t.TempDir(), ".docker", "config.json")
+
+			if tt.existing != "" {
+				assert.NoError(t, os.MkdirAll(filepath.Dir(tmpFile), 0o700))
+				assert.NoError(t, os.WriteFile(tmpFile, []byte(tt.existing), 0o600))
+			}
 
-			err := WriteDockerConf(tmpFile, tt.conf)
+			err := WriteDockerConf(tmpFile, tt.conf, tt.credHelpers)
 			if tt.wantErr {
-				assert.ErrorAs(t, err, &errInvalidDockerConfig)
+				assert.ErrorIs(t, err, docker.ErrInvalidConfig)
 
 				return
 			}
@@ -44,16 +72,7 @@ func TestWriteDockerConf(t *testing.T) {
 
 			content, err := os.ReadFile(tmpFile)
 			assert.NoError(t, err, "Failed to read config file")
-
-			var got, want interface{}
-
-			err = json.Unmarshal(content, &got)
-			assert.NoError(t, err, "Failed to parse written config")
-
-			err = json.Unmarshal([]byte(tt.conf), &want)
-			assert.NoError(t, err, "Failed to parse test config")
-
-			assert.Equal(t, want, got, "Written config does not match expected")
+			assert.JSONEq(t, tt.want, string(content), "Written config does not match expected")
 		})
 	}
 }
@@ -134,8 +153,147 @@ func TestGenerateLabels(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := tt.plugin.GenerateLabels()
+			got := tt.plugin.GenerateLabels(&tt.plugin.Settings.Build)
 			assert.ElementsMatch(t, tt.wantLabels, got)
 		})
 	}
 }
+
+func TestValidateCredHelpers(t *testing.T) {
+	binDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(binDir, "docker-credential-exec"), []byte("#!/bin/sh\n"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(binDir, "docker-credential-noexec"), []byte("#!/bin/sh\n"), 0o644))
+	t.Setenv("PATH", binDir)
+
+	tests := []struct {
+		name    string
+		helpers map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "executable helper",
+			helpers: map[string]string{"ghcr.io": "exec"},
+		},
+		{
+			name:    "not executable helper",
+			helpers: map[string]string{"ghcr.io": "noexec"},
+			wantErr: true,
+		},
+		{
+			name:    "missing helper",
+			helpers: map[string]string{"ghcr.io": "missing"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCredHelpers(tt.helpers)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCredHelper)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), perm))
+		assert.NoError(t, os.Chmod(path, perm))
+
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "trim trailing newlines",
+			path: writeFile("password", "secret\n\n", 0o600),
+			want: "secret",
+		},
+		{
+			name: "trim trailing carriage return",
+			path: writeFile("password-crlf", "secret\r\n", 0o640),
+			want: "secret",
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+		{
+			name:    "world-readable file",
+			path:    writeFile("password-public", "secret", 0o644),
+			wantErr: true,
+		},
+		{
+			name:    "directory",
+			path:    dir,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSecretFile(tt.path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSecretFile)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	passwordFile := filepath.Join(dir, "password")
+	assert.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0o600))
+
+	configFile := filepath.Join(dir, "config.json")
+	assert.NoError(t, os.WriteFile(configFile, []byte(`{"auths": {}}`+"\n"), 0o600))
+
+	t.Run("read files", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{
+			PasswordFile:       passwordFile,
+			RegistryConfigFile: configFile,
+		}}
+
+		assert.NoError(t, p.ReadSecretFiles())
+		assert.Equal(t, "secret", p.Settings.Registry.Password)
+		assert.Equal(t, `{"auths": {}}`, p.Settings.Registry.Config)
+		assert.Empty(t, p.Settings.BuildkitConfig)
+	})
+
+	t.Run("conflicting inline value", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{
+			PasswordFile: passwordFile,
+			Registry:     docker.Registry{Password: "inline"},
+		}}
+
+		assert.ErrorIs(t, p.ReadSecretFiles(), ErrConflictingSecrets)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		p := &Plugin{Settings: &Settings{
+			BuildkitConfigFile: filepath.Join(dir, "buildkit.toml"),
+		}}
+
+		assert.ErrorIs(t, p.ReadSecretFiles(), ErrInvalidSecretFile)
+	})
+}